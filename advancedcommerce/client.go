@@ -0,0 +1,106 @@
+package advancedcommerce
+
+import (
+	"fmt"
+	"github.com/pkg6/applego/applepay"
+)
+
+const (
+	// Cancel a Subscription
+	cancelSubscription = "/advancedCommerce/v1/subscription/cancel/%s" // transactionId
+	// Revoke a Subscription
+	revokeSubscription = "/advancedCommerce/v1/subscription/revoke/%s" // transactionId
+	// Change Subscription Metadata
+	changeSubscriptionMetadata = "/advancedCommerce/v1/subscription/changeMetadata/%s" // transactionId
+	// Change Subscription Price
+	changeSubscriptionPrice = "/advancedCommerce/v1/subscription/changePrice/%s" // transactionId
+	// Migrate a Subscription to Advanced Commerce API
+	migrateSubscription = "/advancedCommerce/v1/subscription/migrate/%s" // transactionId
+	// Request Transaction Refund
+	requestTransactionRefund = "/advancedCommerce/v1/transaction/requestRefund/%s" // transactionId
+)
+
+// Client Advanced Commerce API 客户端，复用 applepay.ApiClient 的鉴权与请求客户端
+// Doc: https://developer.apple.com/documentation/advancedcommerceapi
+type Client struct {
+	Api *applepay.ApiClient
+}
+
+func NewClient(api *applepay.ApiClient) *Client {
+	return &Client{Api: api}
+}
+
+// CancelSubscription Cancel a Subscription
+// Doc: https://developer.apple.com/documentation/advancedcommerceapi/cancel-a-subscription
+func (c *Client) CancelSubscription(transactionId string, req *SubscriptionCancelRequest) (*Response, error) {
+	if err := req.RequestInfo.validate(); err != nil {
+		return nil, err
+	}
+	return c.post(cancelSubscription, transactionId, req)
+}
+
+// RevokeSubscription Revoke a Subscription
+// Doc: https://developer.apple.com/documentation/advancedcommerceapi/revoke-a-subscription
+func (c *Client) RevokeSubscription(transactionId string, req *SubscriptionRevokeRequest) (*Response, error) {
+	if err := req.RequestInfo.validate(); err != nil {
+		return nil, err
+	}
+	return c.post(revokeSubscription, transactionId, req)
+}
+
+// ChangeSubscriptionMetadata Change Subscription Metadata
+// Doc: https://developer.apple.com/documentation/advancedcommerceapi/change-subscription-metadata
+func (c *Client) ChangeSubscriptionMetadata(transactionId string, req *SubscriptionChangeMetadataRequest) (*Response, error) {
+	if err := req.RequestInfo.validate(); err != nil {
+		return nil, err
+	}
+	return c.post(changeSubscriptionMetadata, transactionId, req)
+}
+
+// ChangeSubscriptionPrice Change Subscription Price
+// Doc: https://developer.apple.com/documentation/advancedcommerceapi/change-subscription-price
+func (c *Client) ChangeSubscriptionPrice(transactionId string, req *SubscriptionPriceChangeRequest) (*Response, error) {
+	if err := req.RequestInfo.validate(); err != nil {
+		return nil, err
+	}
+	return c.post(changeSubscriptionPrice, transactionId, req)
+}
+
+// MigrateSubscription Migrate a Subscription to Advanced Commerce API
+// Doc: https://developer.apple.com/documentation/advancedcommerceapi/migrate-a-subscription-to-advanced-commerce-api
+func (c *Client) MigrateSubscription(transactionId string, req *SubscriptionMigrateRequest) (*Response, error) {
+	if err := req.RequestInfo.validate(); err != nil {
+		return nil, err
+	}
+	return c.post(migrateSubscription, transactionId, req)
+}
+
+// RequestRefund Request Transaction Refund
+// Doc: https://developer.apple.com/documentation/advancedcommerceapi/request-transaction-refund
+func (c *Client) RequestRefund(transactionId string, req *RequestRefundRequest) (*Response, error) {
+	if err := req.RequestInfo.validate(); err != nil {
+		return nil, err
+	}
+	return c.post(requestTransactionRefund, transactionId, req)
+}
+
+// DecodeTransactionInfo 使用 ApiClient 的 Verifier 校验并解析响应中的 signedTransactionInfo
+func (c *Client) DecodeTransactionInfo(resp *Response) (*applepay.TransactionInfo, error) {
+	return c.Api.SignedDataVerifier().DecodeTransactionInfo(resp.SignedTransactionInfo)
+}
+
+// DecodeRenewalInfo 使用 ApiClient 的 Verifier 校验并解析响应中的 signedRenewalInfo
+func (c *Client) DecodeRenewalInfo(resp *Response) (*applepay.RenewalInfo, error) {
+	return c.Api.SignedDataVerifier().DecodeRenewalInfo(resp.SignedRenewalInfo)
+}
+
+func (c *Client) post(format, transactionId string, req any) (*Response, error) {
+	resp := new(Response)
+	if err := c.Api.WithTokenPost(fmt.Sprintf(format, transactionId), req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.ErrorCode != 0 {
+		return resp, fmt.Errorf("advanced commerce: %d %s", resp.ErrorCode, resp.ErrorMessage)
+	}
+	return resp, nil
+}
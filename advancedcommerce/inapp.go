@@ -0,0 +1,203 @@
+package advancedcommerce
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/pkg6/applego/applepay"
+)
+
+// AudienceAdvancedCommerce 应用内请求 JWS 签名的 aud
+const AudienceAdvancedCommerce = "advanced-commerce-api"
+
+// 应用内请求的 operation
+const (
+	OperationCreateOneTimeCharge    = "CREATE_ONE_TIME_CHARGE"
+	OperationCreateSubscription     = "CREATE_SUBSCRIPTION"
+	OperationModifySubscription     = "MODIFY_SUBSCRIPTION"
+	OperationReactivateSubscription = "REACTIVATE_SUBSCRIPTION"
+)
+
+// InAppRequestVersion 应用内请求的默认 version
+const InAppRequestVersion = "1"
+
+// InAppRequest 由服务端签名、应用通过 StoreKit 发起的请求
+type InAppRequest interface {
+	prepare() error
+}
+
+// SignInAppRequest 将请求JSON以base64放入 request 声明，生成应用内购买所需的 JWS
+// Doc: https://developer.apple.com/documentation/advancedcommerceapi/signing-a-request-for-in-app-purchases
+func SignInAppRequest(signer *applepay.OfferSigner, req InAppRequest) (string, error) {
+	if err := req.prepare(); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	return signer.Sign(AudienceAdvancedCommerce, &applepay.OfferSignatureClaims{
+		Request: base64.StdEncoding.EncodeToString(data),
+	})
+}
+
+// Offer 订阅优惠
+// https://developer.apple.com/documentation/advancedcommerceapi/offer
+type Offer struct {
+	Period      string `json:"period"`
+	PeriodCount int    `json:"periodCount"`
+	Price       int64  `json:"price"`
+	Reason      string `json:"reason"` // ACQUISITION WIN_BACK RETENTION
+}
+
+// OneTimeChargeCreateRequest
+// https://developer.apple.com/documentation/advancedcommerceapi/onetimechargecreaterequest
+type OneTimeChargeCreateRequest struct {
+	Operation   string             `json:"operation"`
+	Version     string             `json:"version"`
+	RequestInfo *RequestInfo       `json:"requestInfo"`
+	Currency    string             `json:"currency"`
+	Item        *OneTimeChargeItem `json:"item"`
+	TaxCode     string             `json:"taxCode"`
+	Storefront  string             `json:"storefront,omitempty"`
+}
+
+type OneTimeChargeItem struct {
+	SKU         string `json:"SKU"`
+	Description string `json:"description"`
+	DisplayName string `json:"displayName"`
+	Price       int64  `json:"price"`
+}
+
+func (r *OneTimeChargeCreateRequest) prepare() error {
+	if r.Item == nil || r.Currency == "" || r.TaxCode == "" {
+		return errors.New("item, currency and taxCode are required")
+	}
+	setOperation(&r.Operation, &r.Version, OperationCreateOneTimeCharge)
+	return r.RequestInfo.validate()
+}
+
+// SubscriptionCreateRequest
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptioncreaterequest
+type SubscriptionCreateRequest struct {
+	Operation             string                    `json:"operation"`
+	Version               string                    `json:"version"`
+	RequestInfo           *RequestInfo              `json:"requestInfo"`
+	Currency              string                    `json:"currency"`
+	Descriptors           *Descriptors              `json:"descriptors"`
+	Items                 []*SubscriptionCreateItem `json:"items"`
+	Period                string                    `json:"period"`
+	PreviousTransactionId string                    `json:"previousTransactionId,omitempty"`
+	TaxCode               string                    `json:"taxCode"`
+	Storefront            string                    `json:"storefront,omitempty"`
+}
+
+type SubscriptionCreateItem struct {
+	SKU         string `json:"SKU"`
+	Description string `json:"description"`
+	DisplayName string `json:"displayName"`
+	Price       int64  `json:"price"`
+	Offer       *Offer `json:"offer,omitempty"`
+}
+
+func (r *SubscriptionCreateRequest) prepare() error {
+	if len(r.Items) == 0 || r.Currency == "" || r.Period == "" || r.TaxCode == "" {
+		return errors.New("items, currency, period and taxCode are required")
+	}
+	setOperation(&r.Operation, &r.Version, OperationCreateSubscription)
+	return r.RequestInfo.validate()
+}
+
+// SubscriptionModifyInAppRequest
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionmodifyinapprequest
+type SubscriptionModifyInAppRequest struct {
+	Operation          string                          `json:"operation"`
+	Version            string                          `json:"version"`
+	RequestInfo        *RequestInfo                    `json:"requestInfo"`
+	AddItems           []*SubscriptionModifyAddItem    `json:"addItems,omitempty"`
+	ChangeItems        []*SubscriptionModifyChangeItem `json:"changeItems,omitempty"`
+	RemoveItems        []*SubscriptionModifyRemoveItem `json:"removeItems,omitempty"`
+	Currency           string                          `json:"currency,omitempty"`
+	Descriptors        *SubscriptionModifyDescriptors  `json:"descriptors,omitempty"`
+	PeriodChange       *SubscriptionModifyPeriodChange `json:"periodChange,omitempty"`
+	RetainBillingCycle bool                            `json:"retainBillingCycle"`
+	TransactionId      string                          `json:"transactionId"`
+	TaxCode            string                          `json:"taxCode,omitempty"`
+	Storefront         string                          `json:"storefront,omitempty"`
+}
+
+type SubscriptionModifyAddItem struct {
+	SKU           string `json:"SKU"`
+	Description   string `json:"description"`
+	DisplayName   string `json:"displayName"`
+	Price         int64  `json:"price"`
+	Offer         *Offer `json:"offer,omitempty"`
+	ProratedPrice int64  `json:"proratedPrice,omitempty"`
+}
+
+type SubscriptionModifyChangeItem struct {
+	CurrentSKU    string `json:"currentSKU"`
+	SKU           string `json:"SKU"`
+	Description   string `json:"description"`
+	DisplayName   string `json:"displayName"`
+	Price         int64  `json:"price"`
+	Offer         *Offer `json:"offer,omitempty"`
+	ProratedPrice int64  `json:"proratedPrice,omitempty"`
+	Effective     string `json:"effective"` // IMMEDIATELY NEXT_BILL_CYCLE
+	Reason        string `json:"reason"`    // UPGRADE DOWNGRADE APPLY_OFFER
+}
+
+type SubscriptionModifyRemoveItem struct {
+	SKU string `json:"SKU"`
+}
+
+type SubscriptionModifyDescriptors struct {
+	Description string `json:"description,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Effective   string `json:"effective"`
+}
+
+type SubscriptionModifyPeriodChange struct {
+	Period    string `json:"period"`
+	Effective string `json:"effective"`
+}
+
+func (r *SubscriptionModifyInAppRequest) prepare() error {
+	if r.TransactionId == "" {
+		return errors.New("transactionId is required")
+	}
+	setOperation(&r.Operation, &r.Version, OperationModifySubscription)
+	return r.RequestInfo.validate()
+}
+
+// SubscriptionReactivateInAppRequest
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionreactivateinapprequest
+type SubscriptionReactivateInAppRequest struct {
+	Operation     string                        `json:"operation"`
+	Version       string                        `json:"version"`
+	RequestInfo   *RequestInfo                  `json:"requestInfo"`
+	Items         []*SubscriptionReactivateItem `json:"items,omitempty"`
+	TransactionId string                        `json:"transactionId"`
+	Storefront    string                        `json:"storefront,omitempty"`
+}
+
+type SubscriptionReactivateItem struct {
+	SKU string `json:"SKU"`
+}
+
+func (r *SubscriptionReactivateInAppRequest) prepare() error {
+	if r.TransactionId == "" {
+		return errors.New("transactionId is required")
+	}
+	setOperation(&r.Operation, &r.Version, OperationReactivateSubscription)
+	return r.RequestInfo.validate()
+}
+
+func setOperation(operation, version *string, op string) {
+	if *operation == "" {
+		*operation = op
+	}
+	if *version == "" {
+		*version = InAppRequestVersion
+	}
+}
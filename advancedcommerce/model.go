@@ -0,0 +1,174 @@
+package advancedcommerce
+
+import (
+	"errors"
+	"github.com/pkg6/applego/applepay"
+	"github.com/pkg6/applego/utility"
+)
+
+// RefundReason
+// https://developer.apple.com/documentation/advancedcommerceapi/refundreason
+const (
+	RefundReasonUnintendedPurchase      = "UNINTENDED_PURCHASE"
+	RefundReasonFulfillmentIssue        = "FULFILLMENT_ISSUE"
+	RefundReasonUnsatisfiedWithPurchase = "UNSATISFIED_WITH_PURCHASE"
+	RefundReasonLegal                   = "LEGAL"
+	RefundReasonOther                   = "OTHER"
+	RefundReasonModifyItemsRefund       = "MODIFY_ITEMS_REFUND"
+	RefundReasonSimulateRefundDecline   = "SIMULATE_REFUND_DECLINE"
+)
+
+// RefundType
+// https://developer.apple.com/documentation/advancedcommerceapi/refundtype
+const (
+	RefundTypeFull     = "FULL"
+	RefundTypeProrated = "PRORATED"
+	RefundTypeCustom   = "CUSTOM"
+)
+
+// Period 订阅周期
+// https://developer.apple.com/documentation/advancedcommerceapi/period
+const (
+	PeriodP1W = "P1W"
+	PeriodP1M = "P1M"
+	PeriodP2M = "P2M"
+	PeriodP3M = "P3M"
+	PeriodP6M = "P6M"
+	PeriodP1Y = "P1Y"
+)
+
+// RequestInfo 每个请求都需要的请求信息，requestReferenceId 用于幂等
+// https://developer.apple.com/documentation/advancedcommerceapi/requestinfo
+type RequestInfo struct {
+	RequestReferenceId string `json:"requestReferenceId"`
+	AppAccountToken    string `json:"appAccountToken,omitempty"`
+	ConsistencyToken   string `json:"consistencyToken,omitempty"`
+}
+
+// NewRequestInfo 生成新的 requestReferenceId
+func NewRequestInfo() (*RequestInfo, error) {
+	id, err := utility.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &RequestInfo{RequestReferenceId: id}, nil
+}
+
+func (r *RequestInfo) validate() error {
+	if r == nil || r.RequestReferenceId == "" {
+		return errors.New("requestInfo.requestReferenceId is required")
+	}
+	return nil
+}
+
+// Descriptors 订阅的名称与描述
+// https://developer.apple.com/documentation/advancedcommerceapi/descriptors
+type Descriptors struct {
+	Description string `json:"description"`
+	DisplayName string `json:"displayName"`
+}
+
+// SubscriptionCancelRequest
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptioncancelrequest
+type SubscriptionCancelRequest struct {
+	RequestInfo *RequestInfo `json:"requestInfo"`
+	Storefront  string       `json:"storefront,omitempty"`
+}
+
+// SubscriptionRevokeRequest
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionrevokerequest
+type SubscriptionRevokeRequest struct {
+	RequestInfo             *RequestInfo `json:"requestInfo"`
+	RefundReason            string       `json:"refundReason"`
+	RefundRiskingPreference bool         `json:"refundRiskingPreference"`
+	RefundType              string       `json:"refundType"`
+	Storefront              string       `json:"storefront,omitempty"`
+}
+
+// SubscriptionChangeMetadataRequest
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionchangemetadatarequest
+type SubscriptionChangeMetadataRequest struct {
+	RequestInfo *RequestInfo                           `json:"requestInfo"`
+	Descriptors *SubscriptionChangeMetadataDescriptors `json:"descriptors,omitempty"`
+	Items       []*SubscriptionChangeMetadataItem      `json:"items,omitempty"`
+	TaxCode     string                                 `json:"taxCode,omitempty"`
+	Storefront  string                                 `json:"storefront,omitempty"`
+}
+
+type SubscriptionChangeMetadataDescriptors struct {
+	Description string `json:"description,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Effective   string `json:"effective"` // IMMEDIATELY NEXT_BILL_CYCLE
+}
+
+type SubscriptionChangeMetadataItem struct {
+	CurrentSKU  string `json:"currentSKU"`
+	SKU         string `json:"SKU,omitempty"`
+	Description string `json:"description,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Effective   string `json:"effective"`
+}
+
+// SubscriptionPriceChangeRequest
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionpricechangerequest
+type SubscriptionPriceChangeRequest struct {
+	RequestInfo *RequestInfo                   `json:"requestInfo"`
+	Currency    string                         `json:"currency"`
+	Items       []*SubscriptionPriceChangeItem `json:"items"`
+	Storefront  string                         `json:"storefront,omitempty"`
+}
+
+type SubscriptionPriceChangeItem struct {
+	SKU           string   `json:"SKU"`
+	Price         int64    `json:"price"` // 以毫为单位，如 9990 表示 9.99
+	DependentSKUs []string `json:"dependentSKUs,omitempty"`
+}
+
+// SubscriptionMigrateRequest 将订阅迁移到 Advanced Commerce API
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionmigraterequest
+type SubscriptionMigrateRequest struct {
+	RequestInfo     *RequestInfo                      `json:"requestInfo"`
+	Descriptors     *Descriptors                      `json:"descriptors"`
+	Items           []*SubscriptionMigrateItem        `json:"items"`
+	RenewalItems    []*SubscriptionMigrateRenewalItem `json:"renewalItems,omitempty"`
+	TargetProductId string                            `json:"targetProductId"`
+	TaxCode         string                            `json:"taxCode"`
+	Storefront      string                            `json:"storefront,omitempty"`
+}
+
+type SubscriptionMigrateItem struct {
+	SKU         string `json:"SKU"`
+	Description string `json:"description"`
+	DisplayName string `json:"displayName"`
+}
+
+type SubscriptionMigrateRenewalItem struct {
+	SKU         string `json:"SKU"`
+	Description string `json:"description"`
+	DisplayName string `json:"displayName"`
+}
+
+// RequestRefundRequest
+// https://developer.apple.com/documentation/advancedcommerceapi/requestrefundrequest
+type RequestRefundRequest struct {
+	RequestInfo             *RequestInfo         `json:"requestInfo"`
+	Items                   []*RequestRefundItem `json:"items"`
+	RefundRiskingPreference bool                 `json:"refundRiskingPreference"`
+	Currency                string               `json:"currency,omitempty"`
+	Storefront              string               `json:"storefront,omitempty"`
+}
+
+type RequestRefundItem struct {
+	SKU          string `json:"SKU"`
+	RefundAmount int64  `json:"refundAmount,omitempty"` // RefundTypeCustom 时必填
+	RefundReason string `json:"refundReason"`
+	RefundType   string `json:"refundType"`
+	Revoke       bool   `json:"revoke"`
+}
+
+// Response 服务端接口的响应，签名数据使用 Client.DecodeTransactionInfo、Client.DecodeRenewalInfo 校验
+type Response struct {
+	applepay.ResponseErrorMessage
+	SignedRenewalInfo     string `json:"signedRenewalInfo,omitempty"`
+	SignedTransactionInfo string `json:"signedTransactionInfo,omitempty"`
+}
@@ -2,6 +2,7 @@ package applepay
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/tls"
 	"errors"
@@ -32,6 +33,20 @@ const (
 	getRefundHistory = "/inApps/v2/refund/lookup/%s" // transactionId
 	// Get Notification History
 	getNotificationHistory = "/inApps/v1/notifications/history"
+	// Retention Messaging: Upload Image / Delete Image
+	retentionImage = "/inApps/v1/messaging/image/%s" // imageIdentifier
+	// Retention Messaging: Get Image List
+	retentionImageList = "/inApps/v1/messaging/image/list"
+	// Retention Messaging: Upload Message / Delete Message
+	retentionMessage = "/inApps/v1/messaging/message/%s" // messageIdentifier
+	// Retention Messaging: Get Message List
+	retentionMessageList = "/inApps/v1/messaging/message/list"
+	// Retention Messaging: Configure Default Message / Delete Default Message
+	retentionDefaultMessage = "/inApps/v1/messaging/default/%s/%s" // productId locale
+	// Send External Purchase Report
+	externalPurchaseReports = "/externalPurchase/v1/reports"
+	// Retrieve External Purchase Report
+	externalPurchaseReport = "/externalPurchase/v1/reports/%s" // requestIdentifier
 )
 
 type ResponseErrorMessage struct {
@@ -85,13 +100,45 @@ type ApiClient struct {
 	IsProduction bool
 	//Parsing private keys
 	PrivateKey *ecdsa.PrivateKey
+	//Signer 不为nil时使用 SignerGenerateJWTToken 生成token，可以是 HSM/KMS 的签名器
+	Signer crypto.Signer
 	//生成token函数
 	GenerateJWTToken GenerateJWTToken
 	//Request client
 	Client *requests.Client
+	//Verifier 校验接口返回的JWS数据，为nil时信任苹果根证书
+	Verifier *SignedDataVerifier
+}
+
+// SignedDataVerifier 返回校验JWS数据使用的 Verifier，未设置时使用苹果根证书
+func (a *ApiClient) SignedDataVerifier() *SignedDataVerifier {
+	if a.Verifier == nil {
+		return defaultSignedDataVerifier
+	}
+	return a.Verifier
 }
 
 func NewApiClient(iss, bid, keyID string, privateKey []byte, isProduction bool) (api *ApiClient, err error) {
+	key, err := utility.EcdsaPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	api = newApiClient(iss, bid, keyID, isProduction)
+	api.PrivateKey = key
+	return api, nil
+}
+
+// NewApiClientWithSigner 使用 crypto.Signer 签名token，私钥不需要加载到内存
+func NewApiClientWithSigner(iss, bid, keyID string, signer crypto.Signer, isProduction bool) (*ApiClient, error) {
+	if signer == nil {
+		return nil, errors.New("signer is nil")
+	}
+	api := newApiClient(iss, bid, keyID, isProduction)
+	api.Signer = signer
+	return api, nil
+}
+
+func newApiClient(iss, bid, keyID string, isProduction bool) (api *ApiClient) {
 	api = &ApiClient{
 		Bid:              bid,
 		Iss:              iss,
@@ -99,10 +146,6 @@ func NewApiClient(iss, bid, keyID string, privateKey []byte, isProduction bool)
 		IsProduction:     isProduction,
 		GenerateJWTToken: DefaultGenerateJWTToken,
 	}
-	api.PrivateKey, err = utility.EcdsaPrivateKey(privateKey)
-	if err != nil {
-		return
-	}
 	dialer := &net.Dialer{
 		Timeout:   30 * time.Second,
 		KeepAlive: 30 * time.Second,
@@ -143,6 +186,9 @@ type CustomClaims struct {
 // BuildJwtToken
 //https://developer.apple.com/documentation/appstoreserverapi/generating_tokens_for_api_requests
 func (a *ApiClient) generateClientSecret() (string, error) {
+	if a.Signer != nil {
+		return SignerGenerateJWTToken(a.Signer, a.Iss, a.Bid, a.KeyID)
+	}
 	if a.GenerateJWTToken == nil {
 		a.GenerateJWTToken = DefaultGenerateJWTToken
 	}
@@ -172,3 +218,11 @@ func (a *ApiClient) WithTokenPut(path string, data, d any) error {
 	a.Client.WithToken(token)
 	return a.Client.AsJson().PutUnmarshal(context.Background(), path, data, d)
 }
+func (a *ApiClient) WithTokenDelete(path string, data, d any) error {
+	token, err := a.generateClientSecret()
+	if err != nil {
+		return err
+	}
+	a.Client.WithToken(token)
+	return a.Client.AsJson().DeleteUnmarshal(context.Background(), path, data, d)
+}
@@ -0,0 +1,100 @@
+package applepay
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ExternalPurchaseReport status
+const (
+	ExternalPurchaseStatusActive            = "ACTIVE"
+	ExternalPurchaseStatusUnrecognizedToken = "UNRECOGNIZED_TOKEN"
+	ExternalPurchaseStatusNoLineItems       = "NO_LINE_ITEMS"
+)
+
+// SendExternalPurchaseReport Send External Purchase Report
+// Doc: https://developer.apple.com/documentation/externalpurchaseserverapi/send-external-purchase-report
+func (a *ApiClient) SendExternalPurchaseReport(report *ExternalPurchaseReport) (resp *ResponseExternalPurchaseReport, err error) {
+	if report == nil || report.RequestIdentifier == "" || report.ExternalPurchaseId == "" {
+		return nil, errors.New("requestIdentifier and externalPurchaseId are required")
+	}
+	resp = new(ResponseExternalPurchaseReport)
+	err = a.WithTokenPut(externalPurchaseReports, report, &resp)
+	return
+}
+
+// RetrieveExternalPurchaseReport Retrieve External Purchase Report
+// Doc: https://developer.apple.com/documentation/externalpurchaseserverapi/retrieve-external-purchase-report
+func (a *ApiClient) RetrieveExternalPurchaseReport(requestIdentifier string) (resp *ResponseExternalPurchaseReport, err error) {
+	resp = new(ResponseExternalPurchaseReport)
+	err = a.WithTokenGet(fmt.Sprintf(externalPurchaseReport, url.PathEscape(requestIdentifier)), nil, &resp)
+	return
+}
+
+// ExternalPurchaseReport
+// Doc: https://developer.apple.com/documentation/externalpurchaseserverapi/externalpurchasereport
+type ExternalPurchaseReport struct {
+	// RequestIdentifier UUID，用于查询上报结果，重复上报时保持不变
+	RequestIdentifier  string                      `json:"requestIdentifier"`
+	ExternalPurchaseId string                      `json:"externalPurchaseId"`
+	Status             string                      `json:"status"`
+	LineItems          []*ExternalPurchaseLineItem `json:"lineItems,omitempty"`
+}
+
+// ExternalPurchaseLineItem
+// Doc: https://developer.apple.com/documentation/externalpurchaseserverapi/lineitem
+type ExternalPurchaseLineItem struct {
+	LineItemId         string `json:"lineItemId"`
+	OriginalLineItemId string `json:"originalLineItemId,omitempty"`
+	PurchaseDate       int64  `json:"purchaseDate"`
+	RefundDate         int64  `json:"refundDate,omitempty"`
+	ProductType        string `json:"productType"` // ONE_TIME_BUY SUBSCRIPTION
+	Quantity           int    `json:"quantity"`
+	Currency           string `json:"currency"`
+	TaxCountry         string `json:"taxCountry"`
+	TaxExclusivePrice  string `json:"taxExclusivePrice"`
+	TaxAmount          string `json:"taxAmount"`
+	TaxRate            string `json:"taxRate"`
+}
+
+type ResponseExternalPurchaseReport struct {
+	ResponseErrorMessage
+	RequestIdentifier  string                         `json:"requestIdentifier"`
+	ExternalPurchaseId string                         `json:"externalPurchaseId"`
+	Status             string                         `json:"status"`
+	Errors             []*ExternalPurchaseReportError `json:"errors,omitempty"`
+}
+
+type ExternalPurchaseReportError struct {
+	Code    string `json:"code"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// DecodeExternalPurchaseToken 解析app通过 ExternalPurchase API 获取的外部购买token（base64编码的JSON）
+// bundleId 不为空时校验token所属的app
+// Doc: https://developer.apple.com/documentation/externalpurchaseserverapi/receiving-and-decoding-external-purchase-tokens
+func DecodeExternalPurchaseToken(token, bundleId string) (*ExternalPurchaseToken, error) {
+	token = strings.TrimRight(strings.TrimSpace(token), "=")
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		if data, err = base64.RawStdEncoding.DecodeString(token); err != nil {
+			return nil, fmt.Errorf("decode external purchase token: %w", err)
+		}
+	}
+	t := new(ExternalPurchaseToken)
+	if err = json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("decode external purchase token: %w", err)
+	}
+	if t.ExternalPurchaseId == "" {
+		return nil, errors.New("externalPurchaseId is empty")
+	}
+	if bundleId != "" && t.BundleID != bundleId {
+		return nil, fmt.Errorf("external purchase token bundleId %s mismatch", t.BundleID)
+	}
+	return t, nil
+}
@@ -0,0 +1,60 @@
+package applepay
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExternalPurchaseReport(t *testing.T) {
+	var method, path string
+	var report ExternalPurchaseReport
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&report)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"requestIdentifier":"6f1b3a52-1c1f-4c44-9d3c-2c0b2a0e4b1e","status":"PROCESSED"}`))
+	}))
+	defer server.Close()
+	api, _ := NewApiClient("issuer", "com.example.app", "ABCDEF1234", []byte(testOfferKey), false)
+	api.Client.SetBaseURL(server.URL)
+
+	_, err := api.SendExternalPurchaseReport(&ExternalPurchaseReport{})
+	assert.NotEqual(t, err, nil)
+
+	resp, err := api.SendExternalPurchaseReport(&ExternalPurchaseReport{
+		RequestIdentifier:  "6f1b3a52-1c1f-4c44-9d3c-2c0b2a0e4b1e",
+		ExternalPurchaseId: "b2158121-7af9-49d4-9561-1f588205523e",
+		Status:             ExternalPurchaseStatusActive,
+		LineItems: []*ExternalPurchaseLineItem{{LineItemId: "1", PurchaseDate: 1698148900000, ProductType: "ONE_TIME_BUY",
+			Quantity: 1, Currency: "EUR", TaxCountry: "FR", TaxExclusivePrice: "8.33", TaxAmount: "1.66", TaxRate: "0.2"}},
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, method, http.MethodPut)
+	assert.Equal(t, path, "/externalPurchase/v1/reports")
+	assert.Equal(t, report.LineItems[0].TaxCountry, "FR")
+	assert.Equal(t, resp.Status, "PROCESSED")
+
+	_, err = api.RetrieveExternalPurchaseReport("6f1b3a52-1c1f-4c44-9d3c-2c0b2a0e4b1e")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, method, http.MethodGet)
+	assert.Equal(t, path, "/externalPurchase/v1/reports/6f1b3a52-1c1f-4c44-9d3c-2c0b2a0e4b1e")
+}
+
+func TestDecodeExternalPurchaseToken(t *testing.T) {
+	token := base64.URLEncoding.EncodeToString([]byte(`{"appAppleId":6462423041,"bundleId":"com.langaiapp.scanner",` +
+		`"tokenCreationDate":1698148900000,"externalPurchaseId":"b2158121-7af9-49d4-9561-1f588205523e","tokenType":"ACQUISITION"}`))
+	decoded, err := DecodeExternalPurchaseToken(token, "com.langaiapp.scanner")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, decoded.ExternalPurchaseId, "b2158121-7af9-49d4-9561-1f588205523e")
+	assert.Equal(t, decoded.AppAppleID, 6462423041)
+	assert.Equal(t, decoded.TokenType, "ACQUISITION")
+
+	_, err = DecodeExternalPurchaseToken(token, "com.example.app")
+	assert.NotEqual(t, err, nil)
+	_, err = DecodeExternalPurchaseToken("not a token", "")
+	assert.NotEqual(t, err, nil)
+}
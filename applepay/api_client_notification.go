@@ -1,18 +1,45 @@
 package applepay
 
+import (
+	"net/url"
+)
+
 // GetNotificationHistory Get Notification History
 // rsp.NotificationHistory[x].SignedPayload use apple.DecodeSignedPayload() to decode
 // Doc: https://developer.apple.com/documentation/appstoreserverapi/get_notification_history
 func (a *ApiClient) GetNotificationHistory(paginationToken string) (resp *ResponseNotificationHistory, err error) {
+	return a.GetNotificationHistoryWithRequest(paginationToken, nil)
+}
+
+// GetNotificationHistoryWithRequest Get Notification History，按时间范围、通知类型、transactionId 等条件查询
+// Doc: https://developer.apple.com/documentation/appstoreserverapi/notificationhistoryrequest
+func (a *ApiClient) GetNotificationHistoryWithRequest(paginationToken string, req *NotificationHistoryRequest) (resp *ResponseNotificationHistory, err error) {
 	resp = new(ResponseNotificationHistory)
 	path := getNotificationHistory
 	if paginationToken != "" {
-		path += "?paginationToken=" + paginationToken
+		path += "?paginationToken=" + url.QueryEscape(paginationToken)
 	}
-	err = a.WithTokenPost(path, nil, &resp)
+	var data any
+	if req != nil {
+		data = req
+	}
+	err = a.WithTokenPost(path, data, &resp)
 	return
 }
 
+// NotificationHistoryRequest
+// Doc: https://developer.apple.com/documentation/appstoreserverapi/notificationhistoryrequest
+type NotificationHistoryRequest struct {
+	// StartDate EndDate 毫秒时间戳，最多查询最近180天
+	StartDate           int64  `json:"startDate"`
+	EndDate             int64  `json:"endDate"`
+	NotificationType    string `json:"notificationType,omitempty"`
+	NotificationSubtype string `json:"notificationSubtype,omitempty"`
+	// OnlyFailures 仅返回未成功送达的通知
+	OnlyFailures  bool   `json:"onlyFailures,omitempty"`
+	TransactionId string `json:"transactionId,omitempty"`
+}
+
 type ResponseNotificationHistory struct {
 	ResponseErrorMessage
 	HasMore             bool                `json:"hasMore"`
@@ -2,14 +2,15 @@ package applepay
 
 import (
 	"fmt"
+	"net/url"
 )
 
 // GetRefundHistory Get Refund History
 // Doc: https://developer.apple.com/documentation/appstoreserverapi/get_refund_history
 func (a *ApiClient) GetRefundHistory(transactionId, revision string) (rsp *ResponseRefundHistory, err error) {
-	resp := new(ResponseRefundHistory)
-	path := fmt.Sprintf(getRefundHistory, transactionId) + "?revision=" + revision
-	err = a.WithTokenGet(path, nil, &resp)
+	rsp = new(ResponseRefundHistory)
+	path := fmt.Sprintf(getRefundHistory, transactionId) + "?revision=" + url.QueryEscape(revision)
+	err = a.WithTokenGet(path, nil, &rsp)
 	return
 }
 
@@ -0,0 +1,30 @@
+package applepay
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetRefundHistory(t *testing.T) {
+	var path, revision string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, revision = r.URL.Path, r.URL.Query().Get("revision")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"hasMore":true,"revision":"next","signedTransactions":["a.b.c"]}`))
+	}))
+	defer server.Close()
+	api, err := NewApiClient("issuer", "com.example.app", "ABCDEF1234", []byte(testOfferKey), false)
+	assert.Equal(t, err, nil)
+	api.Client.SetBaseURL(server.URL)
+
+	// revision 中的 +、/、& 需要转义，否则苹果收到的 revision 会被截断或改变
+	rsp, err := api.GetRefundHistory("1000", "a+b/c&d=")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, path, "/inApps/v2/refund/lookup/1000")
+	assert.Equal(t, revision, "a+b/c&d=")
+	assert.Equal(t, rsp.HasMore, true)
+	assert.Equal(t, rsp.Revision, "next")
+	assert.Equal(t, len(rsp.SignedTransactions), 1)
+}
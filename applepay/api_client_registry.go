@@ -0,0 +1,148 @@
+package applepay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	EnvironmentProduction = "Production"
+	EnvironmentSandbox    = "Sandbox"
+)
+
+// ErrApiClientNotFound 没有注册对应 bundleId 与环境的 ApiClient
+var ErrApiClientNotFound = errors.New("api client not found")
+
+type apiClientKey struct {
+	bundleId    string
+	environment string
+}
+
+// ApiClientRegistry 按 bundleId 与环境保存多个app的 ApiClient，一个webhook可以处理所有app的通知
+type ApiClientRegistry struct {
+	mu      sync.RWMutex
+	clients map[apiClientKey]*ApiClient
+}
+
+func NewApiClientRegistry(clients ...*ApiClient) *ApiClientRegistry {
+	r := &ApiClientRegistry{clients: make(map[apiClientKey]*ApiClient)}
+	for _, api := range clients {
+		r.Register(api)
+	}
+	return r
+}
+
+// Register 注册 ApiClient，环境由 IsProduction 决定，相同 bundleId 与环境的会被替换
+func (r *ApiClientRegistry) Register(api *ApiClient) {
+	environment := EnvironmentSandbox
+	if api.IsProduction {
+		environment = EnvironmentProduction
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.clients == nil {
+		r.clients = make(map[apiClientKey]*ApiClient)
+	}
+	r.clients[apiClientKey{bundleId: api.Bid, environment: environment}] = api
+}
+
+// Get 返回 bundleId 与环境对应的 ApiClient
+// environment 为空时优先返回正式环境，Xcode、LocalTesting 等环境没有对应的 App Store Server API
+func (r *ApiClientRegistry) Get(bundleId, environment string) (*ApiClient, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if environment == "" {
+		for _, env := range []string{EnvironmentProduction, EnvironmentSandbox} {
+			if api, ok := r.clients[apiClientKey{bundleId: bundleId, environment: env}]; ok {
+				return api, nil
+			}
+		}
+	} else if api, ok := r.clients[apiClientKey{bundleId: bundleId, environment: normalizeEnvironment(environment)}]; ok {
+		return api, nil
+	}
+	return nil, fmt.Errorf("%w: bundleId %q environment %q", ErrApiClientNotFound, bundleId, environment)
+}
+
+// ForNotification 根据通知中的 bundleId 与环境返回 ApiClient
+func (r *ApiClientRegistry) ForNotification(n *NotificationV2SignedPayloadResponse) (*ApiClient, error) {
+	bundleId := NotificationBundleId(n)
+	if bundleId == "" {
+		return nil, errors.New("notification has no bundleId")
+	}
+	return r.Get(bundleId, NotificationEnvironment(n))
+}
+
+// ForTransaction 根据交易中的 bundleId 与环境返回 ApiClient
+func (r *ApiClientRegistry) ForTransaction(ti *TransactionInfo) (*ApiClient, error) {
+	return r.Get(ti.BundleId, ti.Environment)
+}
+
+// BundleIds 已注册的 bundleId
+func (r *ApiClientRegistry) BundleIds() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seen := make(map[string]bool, len(r.clients))
+	ids := make([]string, 0, len(r.clients))
+	for key := range r.clients {
+		if !seen[key.bundleId] {
+			seen[key.bundleId] = true
+			ids = append(ids, key.bundleId)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+type apiClientContextKey struct{}
+
+// ApiClientFromContext 返回 ApiClientRegistry.Handle 放入context的 ApiClient
+func ApiClientFromContext(ctx context.Context) (*ApiClient, bool) {
+	api, ok := ctx.Value(apiClientContextKey{}).(*ApiClient)
+	return api, ok
+}
+
+// Handle 按通知的 bundleId 与环境找到 ApiClient 后调用 fn，可以通过 ApiClientFromContext 获取
+// 未注册的app返回错误，苹果会重试该通知
+func (r *ApiClientRegistry) Handle(fn func(ctx context.Context, api *ApiClient, n *NotificationV2SignedPayloadResponse) error) NotificationV2HandlerFunc {
+	return func(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+		api, err := r.ForNotification(n)
+		if err != nil {
+			return err
+		}
+		return fn(context.WithValue(ctx, apiClientContextKey{}, api), api, n)
+	}
+}
+
+// NotificationBundleId 通知所属app的 bundleId
+func NotificationBundleId(n *NotificationV2SignedPayloadResponse) string {
+	if n == nil || n.Payload == nil {
+		return ""
+	}
+	switch {
+	case n.Payload.Data != nil && n.Payload.Data.BundleID != "":
+		return n.Payload.Data.BundleID
+	case n.Payload.Summary != nil:
+		return n.Payload.Summary.BundleID
+	case n.Payload.ExternalPurchaseToken != nil:
+		return n.Payload.ExternalPurchaseToken.BundleID
+	case n.Payload.AppData != nil:
+		return n.Payload.AppData.BundleID
+	case n.TransactionInfo != nil:
+		return n.TransactionInfo.BundleId
+	}
+	return ""
+}
+
+func normalizeEnvironment(environment string) string {
+	switch strings.ToLower(environment) {
+	case "production", "prod":
+		return EnvironmentProduction
+	case "sandbox":
+		return EnvironmentSandbox
+	}
+	return environment
+}
@@ -0,0 +1,48 @@
+package applepay
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestApiClientRegistry(t *testing.T) {
+	newApi := func(bid string, isProduction bool) *ApiClient {
+		api, err := NewApiClient("57246542-96fe-1a63-e053-0824d011072a", bid, "ABCDEF1234", []byte(testOfferKey), isProduction)
+		assert.NoError(t, err)
+		return api
+	}
+	appProd, appSandbox, other := newApi("com.example.app", true), newApi("com.example.app", false), newApi("com.example.other", false)
+	registry := NewApiClientRegistry(appProd, appSandbox, other)
+	assert.Equal(t, registry.BundleIds(), []string{"com.example.app", "com.example.other"})
+
+	api, err := registry.Get("com.example.app", "Sandbox")
+	assert.NoError(t, err)
+	assert.Equal(t, api, appSandbox)
+	api, _ = registry.Get("com.example.app", "")
+	assert.Equal(t, api, appProd)
+	api, _ = registry.Get("com.example.other", "")
+	assert.Equal(t, api, other)
+	_, err = registry.Get("com.example.other", "Production")
+	assert.True(t, errors.Is(err, ErrApiClientNotFound))
+
+	api, err = registry.ForTransaction(&TransactionInfo{BundleId: "com.example.app", Environment: "Production"})
+	assert.NoError(t, err)
+	assert.Equal(t, api, appProd)
+
+	n := &NotificationV2SignedPayloadResponse{Payload: &NotificationV2Payload{Data: &Data{BundleID: "com.example.app", Environment: "Sandbox"}}}
+	var handled *ApiClient
+	handler := registry.Handle(func(ctx context.Context, api *ApiClient, n *NotificationV2SignedPayloadResponse) error {
+		fromCtx, ok := ApiClientFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, fromCtx, api)
+		handled = api
+		return nil
+	})
+	assert.NoError(t, handler(context.Background(), n))
+	assert.Equal(t, handled, appSandbox)
+
+	n.Payload.Data.BundleID = "com.example.unknown"
+	assert.True(t, errors.Is(handler(context.Background(), n), ErrApiClientNotFound))
+}
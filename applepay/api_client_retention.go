@@ -0,0 +1,137 @@
+package applepay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/pkg6/go-requests"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Retention Messaging 消息与图片的审核状态
+const (
+	RetentionStatePending  = "PENDING"
+	RetentionStateApproved = "APPROVED"
+	RetentionStateRejected = "REJECTED"
+)
+
+// UploadRetentionImage Upload Image，image为PNG图片内容
+// Doc: https://developer.apple.com/documentation/retentionmessaging/upload-image
+func (a *ApiClient) UploadRetentionImage(imageIdentifier string, image []byte) error {
+	token, err := a.generateClientSecret()
+	if err != nil {
+		return err
+	}
+	// 单独构造请求，不修改共享 Client 的 Content-Type，避免影响并发的JSON请求
+	uri := fmt.Sprintf(retentionImage, url.PathEscape(imageIdentifier))
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPut, a.Client.BaseUrl+uri, bytes.NewReader(image))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "image/png")
+	if ua := a.Client.Header.Get("User-Agent"); ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	client := a.Client.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusBadRequest {
+		return &requests.RequestError{URI: uri, Method: http.MethodPut, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// DeleteRetentionImage Delete Image
+// Doc: https://developer.apple.com/documentation/retentionmessaging/delete-image
+func (a *ApiClient) DeleteRetentionImage(imageIdentifier string) error {
+	return a.WithTokenDelete(fmt.Sprintf(retentionImage, url.PathEscape(imageIdentifier)), nil, nil)
+}
+
+// GetRetentionImageList Get Image List
+// Doc: https://developer.apple.com/documentation/retentionmessaging/get-image-list
+func (a *ApiClient) GetRetentionImageList() (resp *ResponseRetentionImageList, err error) {
+	resp = new(ResponseRetentionImageList)
+	err = a.WithTokenGet(retentionImageList, nil, &resp)
+	return
+}
+
+// UploadRetentionMessage Upload Message
+// Doc: https://developer.apple.com/documentation/retentionmessaging/upload-message
+func (a *ApiClient) UploadRetentionMessage(messageIdentifier string, message *RetentionMessage) error {
+	return a.WithTokenPut(fmt.Sprintf(retentionMessage, url.PathEscape(messageIdentifier)), message, nil)
+}
+
+// DeleteRetentionMessage Delete Message
+// Doc: https://developer.apple.com/documentation/retentionmessaging/delete-message
+func (a *ApiClient) DeleteRetentionMessage(messageIdentifier string) error {
+	return a.WithTokenDelete(fmt.Sprintf(retentionMessage, url.PathEscape(messageIdentifier)), nil, nil)
+}
+
+// GetRetentionMessageList Get Message List
+// Doc: https://developer.apple.com/documentation/retentionmessaging/get-message-list
+func (a *ApiClient) GetRetentionMessageList() (resp *ResponseRetentionMessageList, err error) {
+	resp = new(ResponseRetentionMessageList)
+	err = a.WithTokenGet(retentionMessageList, nil, &resp)
+	return
+}
+
+// ConfigureDefaultRetentionMessage Configure Default Message，实时接口无响应时展示该消息
+// Doc: https://developer.apple.com/documentation/retentionmessaging/configure-default-message
+func (a *ApiClient) ConfigureDefaultRetentionMessage(productId, locale, messageIdentifier string) error {
+	path := fmt.Sprintf(retentionDefaultMessage, url.PathEscape(productId), url.PathEscape(locale))
+	return a.WithTokenPut(path, &DefaultRetentionMessage{MessageIdentifier: messageIdentifier}, nil)
+}
+
+// DeleteDefaultRetentionMessage Delete Default Message
+// Doc: https://developer.apple.com/documentation/retentionmessaging/delete-default-message
+func (a *ApiClient) DeleteDefaultRetentionMessage(productId, locale string) error {
+	path := fmt.Sprintf(retentionDefaultMessage, url.PathEscape(productId), url.PathEscape(locale))
+	return a.WithTokenDelete(path, nil, nil)
+}
+
+// RetentionMessage
+// Doc: https://developer.apple.com/documentation/retentionmessaging/uploadmessagerequestbody
+type RetentionMessage struct {
+	Header string                 `json:"header"`
+	Body   string                 `json:"body"`
+	Image  *RetentionMessageImage `json:"image,omitempty"`
+}
+
+type RetentionMessageImage struct {
+	ImageIdentifier string `json:"imageIdentifier"`
+	AltText         string `json:"altText"`
+}
+
+type DefaultRetentionMessage struct {
+	MessageIdentifier string `json:"messageIdentifier"`
+}
+
+type ResponseRetentionImageList struct {
+	ResponseErrorMessage
+	ImageIdentifiers []*RetentionImageState `json:"imageIdentifiers"`
+}
+
+type RetentionImageState struct {
+	ImageIdentifier string `json:"imageIdentifier"`
+	ImageState      string `json:"imageState"`
+}
+
+type ResponseRetentionMessageList struct {
+	ResponseErrorMessage
+	MessageIdentifiers []*RetentionMessageState `json:"messageIdentifiers"`
+}
+
+type RetentionMessageState struct {
+	MessageIdentifier string `json:"messageIdentifier"`
+	MessageState      string `json:"messageState"`
+}
@@ -0,0 +1,72 @@
+package applepay
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkg6/go-requests"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRetentionMessaging(t *testing.T) {
+	var method, path, contentType, authorization string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, contentType = r.Method, r.URL.Path, r.Header.Get("Content-Type")
+		authorization = r.Header.Get("Authorization")
+		body, _ = io.ReadAll(r.Body)
+		if r.URL.Path == retentionMessageList {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"messageIdentifiers":[{"messageIdentifier":"stay","messageState":"APPROVED"}]}`))
+		}
+	}))
+	defer server.Close()
+	api, err := NewApiClient("issuer", "com.example.app", "ABCDEF1234", []byte(testOfferKey), false)
+	assert.Equal(t, err, nil)
+	api.Client.SetBaseURL(server.URL)
+
+	assert.Equal(t, api.UploadRetentionImage("banner", []byte("\x89PNG")), nil)
+	assert.Equal(t, method, http.MethodPut)
+	assert.Equal(t, path, "/inApps/v1/messaging/image/banner")
+	assert.Equal(t, contentType, "image/png")
+	assert.Equal(t, string(body), "\x89PNG")
+	assert.Equal(t, strings.HasPrefix(authorization, "Bearer "), true)
+	// 上传图片不能修改共享 Client 的 Content-Type
+	assert.Equal(t, api.Client.Header.Get("Content-Type"), "")
+
+	message := &RetentionMessage{Header: "Before you go", Body: "Get 50% off", Image: &RetentionMessageImage{ImageIdentifier: "banner", AltText: "sale"}}
+	assert.Equal(t, api.UploadRetentionMessage("stay", message), nil)
+	assert.Equal(t, contentType, "application/json")
+	uploaded := new(RetentionMessage)
+	_ = json.Unmarshal(body, uploaded)
+	assert.Equal(t, uploaded.Image.ImageIdentifier, "banner")
+
+	list, err := api.GetRetentionMessageList()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, list.MessageIdentifiers[0].MessageState, RetentionStateApproved)
+
+	assert.Equal(t, api.ConfigureDefaultRetentionMessage("com.example.monthly", "en-US", "stay"), nil)
+	assert.Equal(t, path, "/inApps/v1/messaging/default/com.example.monthly/en-US")
+
+	assert.Equal(t, api.DeleteRetentionMessage("stay"), nil)
+	assert.Equal(t, method, http.MethodDelete)
+	assert.Equal(t, path, "/inApps/v1/messaging/message/stay")
+}
+
+func TestUploadRetentionImageError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer server.Close()
+	api, err := NewApiClient("issuer", "com.example.app", "ABCDEF1234", []byte(testOfferKey), false)
+	assert.Equal(t, err, nil)
+	api.Client.SetBaseURL(server.URL)
+	err = api.UploadRetentionImage("banner", []byte("\x89PNG"))
+	var reqErr *requests.RequestError
+	assert.Equal(t, errors.As(err, &reqErr), true)
+	assert.Equal(t, reqErr.StatusCode, http.StatusConflict)
+}
@@ -3,13 +3,22 @@ package applepay
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"strconv"
 )
 
-// GetAllSubscriptionStatuses Get All Subscription Statuses
+// GetAllSubscriptionStatuses Get All Subscription Statuses，status 不为空时只返回这些状态的订阅
 // Doc: https://developer.apple.com/documentation/appstoreserverapi/get_all_subscription_statuses
-func (a *ApiClient) GetAllSubscriptionStatuses(transactionId string) (resp *ResponseAllSubscriptionStatuses, err error) {
+func (a *ApiClient) GetAllSubscriptionStatuses(transactionId string, status ...int) (resp *ResponseAllSubscriptionStatuses, err error) {
 	resp = new(ResponseAllSubscriptionStatuses)
 	path := fmt.Sprintf(getAllSubscriptionStatuses, transactionId)
+	if len(status) > 0 {
+		query := url.Values{}
+		for _, st := range status {
+			query.Add("status", strconv.Itoa(st))
+		}
+		path += "?" + query.Encode()
+	}
 	err = a.WithTokenGet(path, nil, &resp)
 	return
 }
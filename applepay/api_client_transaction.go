@@ -51,6 +51,9 @@ type TransactionsItem struct {
 	OfferType                   int    `json:"offerType"`
 	Environment                 string `json:"environment"`
 	AppAccountToken             string `json:"appAccountToken"`
+	IsUpgraded                  bool   `json:"isUpgraded"`
+	RevocationDate              int64  `json:"revocationDate"`
+	RevocationReason            int    `json:"revocationReason"`
 }
 
 // GetTransactionInfo Get Transaction Info
@@ -1,16 +1,14 @@
 package applepay
 
 import (
+	"crypto"
 	"crypto/ecdsa"
-	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	jwt2 "github.com/golang-jwt/jwt"
 	"github.com/pkg6/applego/jwt"
-	"github.com/pkg6/applego/utility"
-	"reflect"
 	"strings"
 	"time"
 )
@@ -38,7 +36,15 @@ at+qIxUCMG1mihDK1A3UT82NQz60imOlM27jbdoXt2QfyFMm+YhidDkLF1vLUagM
 
 // DefaultGenerateJWTToken 生成token
 func DefaultGenerateJWTToken(privateKey *ecdsa.PrivateKey, iss, bid, keyID string) (string, error) {
-	return jwt.Encode(privateKey, jwt2.SigningMethodES256, CustomClaims{
+	if privateKey == nil {
+		return "", errors.New("private key is nil")
+	}
+	return SignerGenerateJWTToken(privateKey, iss, bid, keyID)
+}
+
+// SignerGenerateJWTToken 使用 crypto.Signer 生成token，私钥可以保存在 HSM/KMS 中
+func SignerGenerateJWTToken(signer crypto.Signer, iss, bid, keyID string) (string, error) {
+	return jwt.EncodeWithSigner(signer, CustomClaims{
 		Iss: iss,
 		Iat: time.Now().Unix(),
 		Exp: time.Now().Add(5 * time.Minute).Unix(),
@@ -55,57 +61,21 @@ func DefaultGenerateJWTToken(privateKey *ecdsa.PrivateKey, iss, bid, keyID strin
 // signedPayload：jws格式数据
 // tran：指针类型的结构体，用于接收解析后的数据
 func ExtractClaims(signedPayload string, tran jwt2.Claims) (err error) {
-	valueOf := reflect.ValueOf(tran)
-	if valueOf.Kind() != reflect.Ptr {
-		return errors.New("tran must be ptr struct")
-	}
-	tokenStr := signedPayload
-	rootCertStr, err := extractHeaderByIndex(tokenStr, 2)
-	if err != nil {
-		return err
-	}
-	intermediaCertStr, err := extractHeaderByIndex(tokenStr, 1)
-	if err != nil {
-		return err
-	}
-	if err = utility.VerifyCert([]byte(rootPEM), rootCertStr, intermediaCertStr); err != nil {
-		return err
-	}
-	_, err = jwt2.ParseWithClaims(tokenStr, tran, func(token *jwt2.Token) (any, error) {
-		return extractPublicKeyFromToken(tokenStr)
-	})
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-// Per doc: https://datatracker.ietf.org/doc/html/rfc7515#section-4.1.6
-func extractPublicKeyFromToken(tokenStr string) (*ecdsa.PublicKey, error) {
-	certStr, err := extractHeaderByIndex(tokenStr, 0)
-	if err != nil {
-		return nil, err
-	}
-	cert, err := x509.ParseCertificate(certStr)
-	if err != nil {
-		return nil, err
-	}
-	switch pk := cert.PublicKey.(type) {
-	case *ecdsa.PublicKey:
-		return pk, nil
-	default:
-		return nil, errors.New("appstore public key must be of type ecdsa.PublicKey")
-	}
+	return defaultSignedDataVerifier.ExtractClaims(signedPayload, tran)
 }
 
+// extractHeaderByIndex 返回JWS header中 x5c[index] 的DER证书
 func extractHeaderByIndex(tokenStr string, index int) ([]byte, error) {
 	if index > 2 {
 		return nil, errors.New("invalid index")
 	}
 	tokenArr := strings.Split(tokenStr, ".")
-	headerByte, err := base64.RawStdEncoding.DecodeString(tokenArr[0])
+	// JWS header 为 base64url 编码，兼容旧的 base64 编码
+	headerByte, err := base64.RawURLEncoding.DecodeString(tokenArr[0])
 	if err != nil {
-		return nil, err
+		if headerByte, err = base64.RawStdEncoding.DecodeString(tokenArr[0]); err != nil {
+			return nil, err
+		}
 	}
 	type Header struct {
 		Alg string   `json:"alg"`
@@ -116,8 +86,8 @@ func extractHeaderByIndex(tokenStr string, index int) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	if len(header.X5c) < index {
-		return nil, fmt.Errorf("index[%d] > header.x5c slice len(%d)", index, len(header.X5c))
+	if len(header.X5c) <= index {
+		return nil, fmt.Errorf("index[%d] >= header.x5c slice len(%d)", index, len(header.X5c))
 	}
 	certByte, err := base64.StdEncoding.DecodeString(header.X5c[index])
 	if err != nil {
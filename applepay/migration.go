@@ -0,0 +1,181 @@
+package applepay
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// MigrationEntitlement 迁移期间同一商品在票据与 App Store Server API 中的对比视图
+type MigrationEntitlement struct {
+	ProductId             string `json:"productId"`
+	OriginalTransactionId string `json:"originalTransactionId"`
+	// Receipt 票据中该商品最新的一笔交易
+	Receipt *LatestReceiptInfo `json:"receipt"`
+	// Transaction App Store Server API 交易历史中该原始交易最新的一笔交易
+	Transaction *TransactionsItem `json:"transaction,omitempty"`
+	// SubscriptionStatus 订阅状态，1:有效 2:过期 3:账单重试 4:宽限期 5:撤销，非订阅商品为0
+	SubscriptionStatus int `json:"subscriptionStatus,omitempty"`
+	// RenewalInfo 订阅续订信息，非订阅商品为nil
+	RenewalInfo *RenewalInfo `json:"renewalInfo,omitempty"`
+	// Mismatches 两个数据源之间的差异
+	Mismatches []string `json:"mismatches,omitempty"`
+}
+
+// ReceiptLatestTransactions 从票据中提取每个商品最新的交易，key为productId
+// 优先使用 latest_receipt_info，没有时使用 receipt.in_app
+func ReceiptLatestTransactions(resp *VerifyResponse) map[string]*LatestReceiptInfo {
+	latest := make(map[string]*LatestReceiptInfo)
+	items := resp.LatestReceiptInfo
+	if len(items) == 0 && resp.Receipt != nil {
+		for _, inApp := range resp.Receipt.InApp {
+			items = append(items, inApp.ToLatestReceiptInfo())
+		}
+	}
+	for _, item := range items {
+		current, ok := latest[item.ProductId]
+		if !ok || receiptPurchaseMilli(item) > receiptPurchaseMilli(current) {
+			latest[item.ProductId] = item
+		}
+	}
+	return latest
+}
+
+// MigrateReceiptData 离线解析票据后调用 MigrateReceipt
+func (a *ApiClient) MigrateReceiptData(receipt string) ([]*MigrationEntitlement, error) {
+	r, err := ParseReceipt(receipt)
+	if err != nil {
+		return nil, err
+	}
+	return a.MigrateReceipt(&VerifyResponse{Receipt: r})
+}
+
+// MigrateReceipt 从 verifyReceipt 迁移到 App Store Server API 的辅助方法
+// 提取票据中每个商品最新的原始交易ID，通过 GetTransactionHistory 与 GetAllSubscriptionStatuses 获取当前状态，用于对比两个数据源
+func (a *ApiClient) MigrateReceipt(resp *VerifyResponse) ([]*MigrationEntitlement, error) {
+	var entitlements []*MigrationEntitlement
+	statuses := make(map[string]*ResponseAllSubscriptionStatuses)
+	latest := ReceiptLatestTransactions(resp)
+	productIds := make([]string, 0, len(latest))
+	for productId := range latest {
+		productIds = append(productIds, productId)
+	}
+	sort.Strings(productIds)
+	for _, productId := range productIds {
+		item := latest[productId]
+		entitlement := &MigrationEntitlement{
+			ProductId:             productId,
+			OriginalTransactionId: item.OriginalTransactionId,
+			Receipt:               item,
+		}
+		transaction, err := a.latestTransaction(item.OriginalTransactionId, productId)
+		if err != nil {
+			return nil, err
+		}
+		entitlement.Transaction = transaction
+		if item.ExpiresDateTimestamp != "" {
+			status, ok := statuses[item.OriginalTransactionId]
+			if !ok {
+				if status, err = a.GetAllSubscriptionStatuses(item.OriginalTransactionId); err != nil {
+					return nil, err
+				}
+				statuses[item.OriginalTransactionId] = status
+			}
+			if err = entitlement.applySubscriptionStatus(a.SignedDataVerifier(), status); err != nil {
+				return nil, err
+			}
+		}
+		entitlement.compare()
+		entitlements = append(entitlements, entitlement)
+	}
+	return entitlements, nil
+}
+
+// latestTransaction 交易历史包含用户所有商品的交易，按 productId 查询并逐页查找该原始交易最新的一笔交易
+func (a *ApiClient) latestTransaction(originalTransactionId, productId string) (*TransactionsItem, error) {
+	query := url.Values{"sort": {"DESCENDING"}, "productId": {productId}}
+	for {
+		history, err := a.GetTransactionHistory(originalTransactionId, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, signed := range history.SignedTransactions {
+			ti := new(TransactionsItem)
+			if err = a.SignedDataVerifier().ExtractClaims(string(signed), ti); err != nil {
+				return nil, err
+			}
+			if ti.OriginalTransactionId == originalTransactionId && ti.ProductId == productId {
+				return ti, nil
+			}
+		}
+		if !history.HasMore || history.Revision == "" {
+			return nil, nil
+		}
+		query.Set("revision", history.Revision)
+	}
+}
+
+func (e *MigrationEntitlement) applySubscriptionStatus(verifier *SignedDataVerifier, resp *ResponseAllSubscriptionStatuses) (err error) {
+	for _, data := range resp.Data {
+		for _, last := range data.LastTransactions {
+			if last.OriginalTransactionId != e.OriginalTransactionId {
+				continue
+			}
+			e.SubscriptionStatus = last.Status
+			if last.SignedRenewalInfo != "" {
+				e.RenewalInfo, err = verifier.DecodeRenewalInfo(last.SignedRenewalInfo)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *MigrationEntitlement) compare() {
+	if e.Transaction == nil {
+		e.Mismatches = append(e.Mismatches, "transaction not found in transaction history")
+		return
+	}
+	if e.Transaction.ProductId != e.ProductId {
+		e.Mismatches = append(e.Mismatches, fmt.Sprintf("productId: receipt %s, api %s", e.ProductId, e.Transaction.ProductId))
+	}
+	if e.Transaction.TransactionId != e.Receipt.TransactionId {
+		e.Mismatches = append(e.Mismatches, fmt.Sprintf("transactionId: receipt %s, api %s", e.Receipt.TransactionId, e.Transaction.TransactionId))
+	}
+	if expires, _ := strconv.ParseInt(e.Receipt.ExpiresDateTimestamp, 10, 64); expires != e.Transaction.ExpiresDate {
+		e.Mismatches = append(e.Mismatches, fmt.Sprintf("expiresDate: receipt %d, api %d", expires, e.Transaction.ExpiresDate))
+	}
+}
+
+// ToLatestReceiptInfo 转换为 LatestReceiptInfo，便于与 latest_receipt_info 统一处理
+func (p *InApp) ToLatestReceiptInfo() *LatestReceiptInfo {
+	return &LatestReceiptInfo{
+		CancellationDate:              p.CancellationDate,
+		CancellationDateTimestamp:     p.CancellationDateTimestamp,
+		CancellationDatePST:           p.CancellationDatePST,
+		CancellationReason:            p.CancellationReason,
+		ExpiresDate:                   p.ExpiresDate,
+		ExpiresDateTimestamp:          p.ExpiresDateTimestamp,
+		ExpiresDatePST:                p.ExpiresDatePST,
+		IsInIntroOfferPeriod:          p.IsInIntroOfferPeriod,
+		IsTrialPeriod:                 p.IsTrialPeriod,
+		OriginalPurchaseDate:          p.OriginalPurchaseDate,
+		OriginalPurchaseDateTimestamp: p.OriginalPurchaseDateTimestamp,
+		OriginalPurchaseDatePST:       p.OriginalPurchaseDatePST,
+		OriginalTransactionId:         p.OriginalTransactionId,
+		ProductId:                     p.ProductId,
+		PromotionalOfferId:            p.PromotionalOfferId,
+		PurchaseDate:                  p.PurchaseDate,
+		PurchaseDateTimestamp:         p.PurchaseDateTimestamp,
+		PurchaseDatePST:               p.PurchaseDatePST,
+		Quantity:                      p.Quantity,
+		TransactionId:                 p.TransactionId,
+		WebOrderLineItemId:            p.WebOrderLineItemId,
+	}
+}
+
+func receiptPurchaseMilli(item *LatestReceiptInfo) int64 {
+	t, _ := item.PurchaseDateTime()
+	return t.UnixMilli()
+}
@@ -0,0 +1,132 @@
+package applepay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// NotificationHistoryClient 查询通知历史，*ApiClient 实现了该接口
+type NotificationHistoryClient interface {
+	GetNotificationHistoryWithRequest(paginationToken string, req *NotificationHistoryRequest) (*ResponseNotificationHistory, error)
+}
+
+// NotificationCheckpoint 保存通知历史的分页位置，用于中断后继续补偿
+type NotificationCheckpoint interface {
+	// Load 不存在时返回空字符串
+	Load(ctx context.Context, key string) (string, error)
+	// Save paginationToken 为空时表示该查询已完成
+	Save(ctx context.Context, key, paginationToken string) error
+}
+
+// MemoryNotificationCheckpoint 基于内存的 NotificationCheckpoint
+type MemoryNotificationCheckpoint struct {
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+func NewMemoryNotificationCheckpoint() *MemoryNotificationCheckpoint {
+	return &MemoryNotificationCheckpoint{tokens: make(map[string]string)}
+}
+
+func (c *MemoryNotificationCheckpoint) Load(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens[key], nil
+}
+
+func (c *MemoryNotificationCheckpoint) Save(_ context.Context, key, paginationToken string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if paginationToken == "" {
+		delete(c.tokens, key)
+		return nil
+	}
+	c.tokens[key] = paginationToken
+	return nil
+}
+
+// NotificationReconcileResult 一次补偿的统计
+type NotificationReconcileResult struct {
+	Pages         int
+	Notifications int
+	// Errors 处理失败的通知，设置了Store时可以通过 NotificationV2Handler.Replay 重新处理
+	Errors NotificationErrors
+}
+
+// NotificationReconciler 从 Get Notification History 拉取通知，交给与webhook相同的 NotificationV2Handler 处理
+// Handler 设置了Store时，已处理的 notificationUUID 会被跳过
+type NotificationReconciler struct {
+	Client  NotificationHistoryClient
+	Handler *NotificationV2Handler
+	// Checkpoint 为nil时不保存分页位置
+	Checkpoint NotificationCheckpoint
+}
+
+func NewNotificationReconciler(client NotificationHistoryClient, handler *NotificationV2Handler, checkpoint NotificationCheckpoint) *NotificationReconciler {
+	return &NotificationReconciler{Client: client, Handler: handler, Checkpoint: checkpoint}
+}
+
+// Reconcile 拉取并处理 req 条件下的所有通知，每处理完一页保存一次分页位置
+// 单条通知处理失败不会中断补偿，记录在 NotificationReconcileResult.Errors 中
+func (r *NotificationReconciler) Reconcile(ctx context.Context, req *NotificationHistoryRequest) (*NotificationReconcileResult, error) {
+	if r.Client == nil || r.Handler == nil {
+		return nil, errors.New("notification reconciler client or handler is nil")
+	}
+	if req == nil {
+		return nil, errors.New("notification history request is nil")
+	}
+	key := req.checkpointKey()
+	result := new(NotificationReconcileResult)
+	token, err := r.loadCheckpoint(ctx, key)
+	if err != nil {
+		return result, err
+	}
+	for {
+		if err = ctx.Err(); err != nil {
+			return result, err
+		}
+		resp, err := r.Client.GetNotificationHistoryWithRequest(token, req)
+		if err != nil {
+			return result, err
+		}
+		if resp.ErrorCode != 0 {
+			return result, fmt.Errorf("notification history: %d %s", resp.ErrorCode, resp.ErrorMessage)
+		}
+		result.Pages++
+		for _, item := range resp.NotificationHistory {
+			result.Notifications++
+			if err = r.Handler.Process(ctx, item.SignedPayload); err != nil {
+				result.Errors = append(result.Errors, err)
+			}
+		}
+		if !resp.HasMore || resp.PaginationToken == "" {
+			return result, r.saveCheckpoint(ctx, key, "")
+		}
+		token = resp.PaginationToken
+		if err = r.saveCheckpoint(ctx, key, token); err != nil {
+			return result, err
+		}
+	}
+}
+
+func (r *NotificationReconciler) loadCheckpoint(ctx context.Context, key string) (string, error) {
+	if r.Checkpoint == nil {
+		return "", nil
+	}
+	return r.Checkpoint.Load(ctx, key)
+}
+
+func (r *NotificationReconciler) saveCheckpoint(ctx context.Context, key, token string) error {
+	if r.Checkpoint == nil {
+		return nil
+	}
+	return r.Checkpoint.Save(ctx, key, token)
+}
+
+// checkpointKey 相同查询条件使用同一个分页位置
+func (req *NotificationHistoryRequest) checkpointKey() string {
+	return fmt.Sprintf("%d-%d-%s-%s-%t-%s", req.StartDate, req.EndDate, req.NotificationType,
+		req.NotificationSubtype, req.OnlyFailures, req.TransactionId)
+}
@@ -0,0 +1,60 @@
+package applepay
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+type fakeNotificationHistoryClient struct {
+	pages  map[string]*ResponseNotificationHistory
+	tokens []string
+	err    error
+}
+
+func (c *fakeNotificationHistoryClient) GetNotificationHistoryWithRequest(paginationToken string, req *NotificationHistoryRequest) (*ResponseNotificationHistory, error) {
+	c.tokens = append(c.tokens, paginationToken)
+	if paginationToken != "" && c.err != nil {
+		return nil, c.err
+	}
+	return c.pages[paginationToken], nil
+}
+
+func TestNotificationReconciler(t *testing.T) {
+	file, _ := os.ReadFile("test_notification_v2_signed_payload.txt")
+	client := &fakeNotificationHistoryClient{
+		pages: map[string]*ResponseNotificationHistory{
+			"":      {HasMore: true, PaginationToken: "page2", NotificationHistory: []*NotificationItem{{SignedPayload: string(file)}}},
+			"page2": {NotificationHistory: []*NotificationItem{{SignedPayload: string(file)}, {SignedPayload: "a.b.c"}}},
+		},
+		err: errors.New("connection reset"),
+	}
+	calls := 0
+	handler := NewNotificationV2Handler(func(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+		calls++
+		return nil
+	})
+	handler.Store = NewMemoryNotificationStore()
+	checkpoint := NewMemoryNotificationCheckpoint()
+	reconciler := NewNotificationReconciler(client, handler, checkpoint)
+	req := &NotificationHistoryRequest{StartDate: 1, EndDate: 2, OnlyFailures: true}
+	ctx := context.Background()
+
+	result, err := reconciler.Reconcile(ctx, req)
+	assert.Equal(t, err, client.err)
+	assert.Equal(t, result.Pages, 1)
+	token, _ := checkpoint.Load(ctx, req.checkpointKey())
+	assert.Equal(t, token, "page2")
+
+	client.err, client.tokens = nil, nil
+	result, err = reconciler.Reconcile(ctx, req)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, client.tokens, []string{"page2"})
+	assert.Equal(t, result.Notifications, 2)
+	assert.Equal(t, len(result.Errors), 1)
+	assert.Equal(t, calls, 1)
+	token, _ = checkpoint.Load(ctx, req.checkpointKey())
+	assert.Equal(t, token, "")
+}
@@ -0,0 +1,341 @@
+package applepay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRelayMaxRetries 转发失败时的默认重试次数
+	DefaultRelayMaxRetries = 3
+	// DefaultRelayRetryWait 默认重试间隔
+	DefaultRelayRetryWait = time.Second
+	// DefaultRelayTimeout 默认的单次转发超时时间
+	DefaultRelayTimeout = 10 * time.Second
+)
+
+var defaultRelayClient = &http.Client{Timeout: DefaultRelayTimeout}
+
+type signedPayloadContextKey struct{}
+
+// SignedPayloadFromContext 返回 NotificationV2Handler.Process 放入ctx的原始signedPayload
+func SignedPayloadFromContext(ctx context.Context) (string, bool) {
+	s, ok := ctx.Value(signedPayloadContextKey{}).(string)
+	return s, ok
+}
+
+// NotificationEnvironment 返回通知所属环境 Sandbox、Production 等
+func NotificationEnvironment(n *NotificationV2SignedPayloadResponse) string {
+	if n == nil || n.Payload == nil {
+		return ""
+	}
+	switch {
+	case n.Payload.Data != nil && n.Payload.Data.Environment != "":
+		return n.Payload.Data.Environment
+	case n.Payload.Summary != nil:
+		return n.Payload.Summary.Environment
+	case n.Payload.AppData != nil:
+		return n.Payload.AppData.Environment
+	case n.TransactionInfo != nil:
+		return n.TransactionInfo.Environment
+	}
+	return ""
+}
+
+// RelayTarget 下游转发地址
+type RelayTarget struct {
+	Name string
+	URL  string
+	// Environments 只转发这些环境的通知，为空时转发全部
+	Environments []string
+	// Decoded 为true时转发解析后的 NotificationV2SignedPayloadResponse，否则转发原始的 {"signedPayload": "..."}
+	// V1通知没有 signedPayload，注册到 NotificationV1Handler 时必须为true
+	Decoded bool
+	Header  http.Header
+	// MaxRetries RetryDeadLetters 的最大重试次数，默认 DefaultRelayMaxRetries，小于0时不重试
+	MaxRetries int
+	// RetryWait 两次重试的最小间隔，默认 DefaultRelayRetryWait
+	RetryWait time.Duration
+}
+
+func (t *RelayTarget) maxRetries() int {
+	if t.MaxRetries == 0 {
+		return DefaultRelayMaxRetries
+	}
+	return t.MaxRetries
+}
+
+func (t *RelayTarget) retryWait() time.Duration {
+	if t.RetryWait <= 0 {
+		return DefaultRelayRetryWait
+	}
+	return t.RetryWait
+}
+
+func (t *RelayTarget) match(environment string) bool {
+	if len(t.Environments) == 0 {
+		return true
+	}
+	for _, e := range t.Environments {
+		if e == environment {
+			return true
+		}
+	}
+	return false
+}
+
+// DeadLetter 重试后仍转发失败的通知
+type DeadLetter struct {
+	Target           string    `json:"target"`
+	URL              string    `json:"url"`
+	NotificationUUID string    `json:"notificationUUID"`
+	NotificationType string    `json:"notificationType"`
+	Subtype          string    `json:"subtype"`
+	Environment      string    `json:"environment"`
+	Body             []byte    `json:"body"`
+	Attempts         int       `json:"attempts"`
+	LastError        string    `json:"lastError"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// DeadLetterStore 保存转发失败的通知，便于排查与重新投递
+type DeadLetterStore interface {
+	Put(ctx context.Context, letter *DeadLetter) error
+	List(ctx context.Context) ([]*DeadLetter, error)
+	Delete(ctx context.Context, target, notificationUUID string) error
+}
+
+// MemoryDeadLetterStore 基于内存的 DeadLetterStore
+type MemoryDeadLetterStore struct {
+	mu      sync.Mutex
+	letters map[string]*DeadLetter
+}
+
+func NewMemoryDeadLetterStore() *MemoryDeadLetterStore {
+	return &MemoryDeadLetterStore{letters: make(map[string]*DeadLetter)}
+}
+
+func (s *MemoryDeadLetterStore) Put(_ context.Context, letter *DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters[letter.Target+"/"+letter.NotificationUUID] = letter
+	return nil
+}
+
+func (s *MemoryDeadLetterStore) List(_ context.Context) ([]*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	letters := make([]*DeadLetter, 0, len(s.letters))
+	for _, letter := range s.letters {
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].CreatedAt.Before(letters[j].CreatedAt)
+	})
+	return letters, nil
+}
+
+func (s *MemoryDeadLetterStore) Delete(_ context.Context, target, notificationUUID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.letters, target+"/"+notificationUUID)
+	return nil
+}
+
+// NotificationRelay 将校验后的通知并发转发到多个下游服务
+// Dispatch 与 NotificationV2HandlerFunc 签名一致，注册到 NotificationV2Handler 后只需校验一次签名
+// Dispatch 对每个下游只转发一次，失败的写入 DeadLetters 后由 Run 在后台重试，不会阻塞苹果的通知请求，
+// 也不会因为一个下游失败导致苹果重新发送而让其他下游收到重复的通知
+type NotificationRelay struct {
+	Targets []*RelayTarget
+	// Client 默认超时时间为 DefaultRelayTimeout
+	Client *http.Client
+	// DeadLetters 保存转发失败的通知，不能为nil，默认 MemoryDeadLetterStore，多实例部署时应使用持久化的存储
+	DeadLetters DeadLetterStore
+}
+
+func NewNotificationRelay(targets ...*RelayTarget) *NotificationRelay {
+	return &NotificationRelay{Targets: targets, DeadLetters: NewMemoryDeadLetterStore()}
+}
+
+// Dispatch 转发一条通知
+func (r *NotificationRelay) Dispatch(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+	if n == nil || n.Payload == nil {
+		return errors.New("notification payload is nil")
+	}
+	if r.DeadLetters == nil {
+		return errors.New("notification relay: dead letter store is nil")
+	}
+	environment := NotificationEnvironment(n)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs NotificationErrors
+	for _, target := range r.Targets {
+		if !target.match(environment) {
+			continue
+		}
+		wg.Add(1)
+		go func(target *RelayTarget) {
+			defer wg.Done()
+			if err := r.relay(ctx, target, n, environment); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(target)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (r *NotificationRelay) relay(ctx context.Context, target *RelayTarget, n *NotificationV2SignedPayloadResponse, environment string) error {
+	body, err := relayBody(ctx, target, n)
+	if err != nil {
+		return fmt.Errorf("relay %s: %w", target.Name, err)
+	}
+	err = r.post(ctx, target, n.Payload.NotificationUUID, n.Payload.NotificationType, body)
+	if err == nil {
+		return nil
+	}
+	now := time.Now()
+	letter := &DeadLetter{
+		Target:           target.Name,
+		URL:              target.URL,
+		NotificationUUID: n.Payload.NotificationUUID,
+		NotificationType: n.Payload.NotificationType,
+		Subtype:          n.Payload.Subtype,
+		Environment:      environment,
+		Body:             body,
+		Attempts:         1,
+		LastError:        fmt.Sprintf("relay %s: %v", target.Name, err),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if putErr := r.DeadLetters.Put(ctx, letter); putErr != nil {
+		return fmt.Errorf("relay %s: %v; dead letter: %w", target.Name, err, putErr)
+	}
+	return nil
+}
+
+// Run 每隔 interval 调用 RetryDeadLetters，直到ctx结束
+func (r *NotificationRelay) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultRelayRetryWait
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			_ = r.RetryDeadLetters(ctx)
+		}
+	}
+}
+
+// RetryDeadLetters 重试距上次转发超过 RetryWait 且未超过 MaxRetries 的死信，成功后删除
+// 超过重试次数的死信保留在 DeadLetters 中，需要通过 Redeliver 手动投递
+func (r *NotificationRelay) RetryDeadLetters(ctx context.Context) error {
+	if r.DeadLetters == nil {
+		return errors.New("dead letter store is nil")
+	}
+	letters, err := r.DeadLetters.List(ctx)
+	if err != nil {
+		return err
+	}
+	var errs NotificationErrors
+	for _, letter := range letters {
+		target := r.target(letter)
+		if letter.Attempts > target.maxRetries() || time.Since(letter.UpdatedAt) < target.retryWait() {
+			continue
+		}
+		if err = r.post(ctx, target, letter.NotificationUUID, letter.NotificationType, letter.Body); err == nil {
+			err = r.DeadLetters.Delete(ctx, letter.Target, letter.NotificationUUID)
+		} else {
+			retry := *letter
+			retry.Attempts++
+			retry.LastError = fmt.Sprintf("relay %s: %v", target.Name, err)
+			retry.UpdatedAt = time.Now()
+			err = r.DeadLetters.Put(ctx, &retry)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Redeliver 重新投递死信，成功后从 DeadLetters 删除
+func (r *NotificationRelay) Redeliver(ctx context.Context, letter *DeadLetter) error {
+	if r.DeadLetters == nil {
+		return errors.New("dead letter store is nil")
+	}
+	if err := r.post(ctx, r.target(letter), letter.NotificationUUID, letter.NotificationType, letter.Body); err != nil {
+		return err
+	}
+	return r.DeadLetters.Delete(ctx, letter.Target, letter.NotificationUUID)
+}
+
+// target 死信对应的下游，已删除的下游使用死信中的地址
+func (r *NotificationRelay) target(letter *DeadLetter) *RelayTarget {
+	for _, t := range r.Targets {
+		if t.Name == letter.Target {
+			return t
+		}
+	}
+	return &RelayTarget{Name: letter.Target, URL: letter.URL, MaxRetries: -1}
+}
+
+func (r *NotificationRelay) post(ctx context.Context, target *RelayTarget, notificationUUID, notificationType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range target.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Apple-Notification-UUID", notificationUUID)
+	req.Header.Set("X-Apple-Notification-Type", notificationType)
+	client := r.Client
+	if client == nil {
+		client = defaultRelayClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func relayBody(ctx context.Context, target *RelayTarget, n *NotificationV2SignedPayloadResponse) ([]byte, error) {
+	if target.Decoded {
+		return json.Marshal(n)
+	}
+	signedPayload, ok := SignedPayloadFromContext(ctx)
+	if !ok {
+		return nil, errors.New("signedPayload not found in context, use Decoded for V1 notifications")
+	}
+	return json.Marshal(NotificationV2Request{SignedPayload: signedPayload})
+}
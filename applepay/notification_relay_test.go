@@ -0,0 +1,94 @@
+package applepay
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNotificationRelay(t *testing.T) {
+	file, _ := os.ReadFile("test_notification_v2_signed_payload.txt")
+	var rawBody NotificationV2Request
+	raw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&rawBody)
+	}))
+	defer raw.Close()
+	var productionCalls int32
+	production := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&productionCalls, 1)
+	}))
+	defer production.Close()
+	var decodedCalls, decodedStatus int32 = 0, http.StatusServiceUnavailable
+	decoded := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&decodedCalls, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&decodedStatus)))
+	}))
+	defer decoded.Close()
+
+	deadLetters := NewMemoryDeadLetterStore()
+	relay := NewNotificationRelay(
+		&RelayTarget{Name: "raw", URL: raw.URL, Environments: []string{"Sandbox"}},
+		&RelayTarget{Name: "production", URL: production.URL, Environments: []string{"Production"}},
+		&RelayTarget{Name: "decoded", URL: decoded.URL, Decoded: true, MaxRetries: 1, RetryWait: time.Millisecond},
+	)
+	relay.DeadLetters = deadLetters
+	handler := NewNotificationV2Handler(relay.Dispatch)
+
+	assert.Equal(t, handler.Process(context.Background(), string(file)), nil)
+	assert.Equal(t, rawBody.SignedPayload, string(file))
+	assert.Equal(t, atomic.LoadInt32(&productionCalls), int32(0))
+	// Dispatch 只转发一次，失败的写入死信
+	assert.Equal(t, atomic.LoadInt32(&decodedCalls), int32(1))
+
+	letters, _ := deadLetters.List(context.Background())
+	assert.Equal(t, len(letters), 1)
+	assert.Equal(t, letters[0].Target, "decoded")
+	assert.Equal(t, letters[0].Attempts, 1)
+
+	// 后台重试，超过 MaxRetries 后不再重试
+	time.Sleep(2 * time.Millisecond)
+	assert.Equal(t, relay.RetryDeadLetters(context.Background()), nil)
+	time.Sleep(2 * time.Millisecond)
+	assert.Equal(t, relay.RetryDeadLetters(context.Background()), nil)
+	assert.Equal(t, atomic.LoadInt32(&decodedCalls), int32(2))
+	letters, _ = deadLetters.List(context.Background())
+	assert.Equal(t, letters[0].Attempts, 2)
+
+	atomic.StoreInt32(&decodedStatus, http.StatusOK)
+	assert.Equal(t, relay.Redeliver(context.Background(), letters[0]), nil)
+	letters, _ = deadLetters.List(context.Background())
+	assert.Equal(t, len(letters), 0)
+}
+
+func TestNotificationRelayRetry(t *testing.T) {
+	var calls int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer target.Close()
+	relay := NewNotificationRelay(&RelayTarget{Name: "decoded", URL: target.URL, Decoded: true, RetryWait: time.Millisecond})
+	n := &NotificationV2SignedPayloadResponse{Payload: &NotificationV2Payload{NotificationUUID: "uuid", NotificationType: NotificationTypeTest}}
+	assert.Equal(t, relay.Dispatch(context.Background(), n), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	go func() { _ = relay.Run(ctx, time.Millisecond) }()
+	for ctx.Err() == nil {
+		if letters, _ := relay.DeadLetters.List(ctx); len(letters) == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(2))
+
+	noStore := &NotificationRelay{Targets: relay.Targets}
+	assert.NotEqual(t, noStore.Dispatch(context.Background(), n), nil)
+}
@@ -0,0 +1,136 @@
+package applepay
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type NotificationStatus string
+
+const (
+	NotificationStatusProcessing NotificationStatus = "PROCESSING"
+	NotificationStatusSucceeded  NotificationStatus = "SUCCEEDED"
+	NotificationStatusFailed     NotificationStatus = "FAILED"
+)
+
+// DefaultNotificationProcessingTimeout 处理中的通知超过该时间未完成时，允许重新处理
+const DefaultNotificationProcessingTimeout = 5 * time.Minute
+
+// NotificationRecord 通知的处理记录
+type NotificationRecord struct {
+	NotificationUUID string             `json:"notificationUUID"`
+	NotificationType string             `json:"notificationType"`
+	Subtype          string             `json:"subtype"`
+	SignedPayload    string             `json:"signedPayload"` // 用于重放
+	Status           NotificationStatus `json:"status"`
+	Attempts         int                `json:"attempts"`
+	LastError        string             `json:"lastError,omitempty"`
+	ReceivedAt       time.Time          `json:"receivedAt"`
+	UpdatedAt        time.Time          `json:"updatedAt"`
+}
+
+// NotificationStore 记录已处理的 notificationUUID，苹果会重复发送同一条通知
+type NotificationStore interface {
+	// Begin 开始处理一条通知，返回false表示已处理成功或正在处理中，应当跳过
+	Begin(ctx context.Context, record *NotificationRecord) (bool, error)
+	// Finish 记录处理结果，err为nil表示处理成功
+	Finish(ctx context.Context, notificationUUID string, err error) error
+	// Get 记录不存在时返回 nil, nil
+	Get(ctx context.Context, notificationUUID string) (*NotificationRecord, error)
+	// List 按接收时间返回指定状态的记录，status为空时返回全部
+	List(ctx context.Context, status NotificationStatus) ([]*NotificationRecord, error)
+}
+
+// shouldProcess 已存在的记录是否需要重新处理
+func shouldProcess(record *NotificationRecord, now time.Time, timeout time.Duration) bool {
+	switch record.Status {
+	case NotificationStatusSucceeded:
+		return false
+	case NotificationStatusProcessing:
+		if timeout <= 0 {
+			timeout = DefaultNotificationProcessingTimeout
+		}
+		return now.Sub(record.UpdatedAt) > timeout
+	}
+	return true
+}
+
+// MemoryNotificationStore 基于内存的 NotificationStore，适用于单机与测试
+type MemoryNotificationStore struct {
+	// ProcessingTimeout 默认 DefaultNotificationProcessingTimeout
+	ProcessingTimeout time.Duration
+	mu                sync.Mutex
+	records           map[string]*NotificationRecord
+}
+
+func NewMemoryNotificationStore() *MemoryNotificationStore {
+	return &MemoryNotificationStore{records: make(map[string]*NotificationRecord)}
+}
+
+func (s *MemoryNotificationStore) Begin(_ context.Context, record *NotificationRecord) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	existing, ok := s.records[record.NotificationUUID]
+	if ok && !shouldProcess(existing, now, s.ProcessingTimeout) {
+		return false, nil
+	}
+	if !ok {
+		existing = &NotificationRecord{
+			NotificationUUID: record.NotificationUUID,
+			NotificationType: record.NotificationType,
+			Subtype:          record.Subtype,
+			SignedPayload:    record.SignedPayload,
+			ReceivedAt:       now,
+		}
+		s.records[record.NotificationUUID] = existing
+	}
+	existing.Status = NotificationStatusProcessing
+	existing.Attempts++
+	existing.UpdatedAt = now
+	return true, nil
+}
+
+func (s *MemoryNotificationStore) Finish(_ context.Context, notificationUUID string, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[notificationUUID]
+	if !ok {
+		return nil
+	}
+	record.Status, record.LastError = NotificationStatusSucceeded, ""
+	if err != nil {
+		record.Status, record.LastError = NotificationStatusFailed, err.Error()
+	}
+	record.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *MemoryNotificationStore) Get(_ context.Context, notificationUUID string) (*NotificationRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[notificationUUID]
+	if !ok {
+		return nil, nil
+	}
+	r := *record
+	return &r, nil
+}
+
+func (s *MemoryNotificationStore) List(_ context.Context, status NotificationStatus) ([]*NotificationRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []*NotificationRecord
+	for _, record := range s.records {
+		if status == "" || record.Status == status {
+			r := *record
+			records = append(records, &r)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ReceivedAt.Before(records[j].ReceivedAt)
+	})
+	return records, nil
+}
@@ -0,0 +1,167 @@
+package applepay
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLNotificationStore 基于 database/sql 的 NotificationStore，驱动由调用方注册
+// 表结构参考 CreateTable，时间以毫秒时间戳保存
+type SQLNotificationStore struct {
+	DB *sql.DB
+	// Table 表名，默认 apple_notifications
+	Table string
+	// Placeholder 参数占位符，MySQL/SQLite 为 "?"（默认），PostgreSQL 为 "$"
+	Placeholder string
+	// ProcessingTimeout 默认 DefaultNotificationProcessingTimeout
+	ProcessingTimeout time.Duration
+}
+
+func NewSQLNotificationStore(db *sql.DB) *SQLNotificationStore {
+	return &SQLNotificationStore{DB: db}
+}
+
+// CreateTable 创建通知记录表
+func (s *SQLNotificationStore) CreateTable(ctx context.Context) error {
+	_, err := s.DB.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.table()+` (
+	notification_uuid VARCHAR(64) NOT NULL PRIMARY KEY,
+	notification_type VARCHAR(64) NOT NULL,
+	subtype VARCHAR(64) NOT NULL,
+	signed_payload TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	attempts INTEGER NOT NULL,
+	last_error TEXT NOT NULL,
+	received_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL
+)`)
+	return err
+}
+
+// Begin 不使用 SELECT 后再写入，由单条 UPDATE/INSERT 的影响行数决定是否处理，并发投递同一条通知时只有一个返回true
+// 先以条件 UPDATE 抢占失败或处理超时的记录，记录不存在时 INSERT，主键冲突说明其他请求已插入
+func (s *SQLNotificationStore) Begin(ctx context.Context, record *NotificationRecord) (bool, error) {
+	now := time.Now()
+	timeout := s.ProcessingTimeout
+	if timeout <= 0 {
+		timeout = DefaultNotificationProcessingTimeout
+	}
+	res, err := s.DB.ExecContext(ctx, s.query(`UPDATE `+s.table()+` SET status = ?, attempts = attempts + 1, updated_at = ? WHERE notification_uuid = ? AND status <> ? AND (status <> ? OR updated_at < ?)`),
+		string(NotificationStatusProcessing), now.UnixMilli(), record.NotificationUUID,
+		string(NotificationStatusSucceeded), string(NotificationStatusProcessing), now.Add(-timeout).UnixMilli())
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err == nil, err
+	}
+	existing, err := s.get(ctx, s.DB, record.NotificationUUID)
+	if err != nil || existing != nil {
+		return false, err
+	}
+	_, err = s.DB.ExecContext(ctx, s.query(`INSERT INTO `+s.table()+` (notification_uuid, notification_type, subtype, signed_payload, status, attempts, last_error, received_at, updated_at) VALUES (?, ?, ?, ?, ?, 1, '', ?, ?)`),
+		record.NotificationUUID, record.NotificationType, record.Subtype, record.SignedPayload, string(NotificationStatusProcessing), now.UnixMilli(), now.UnixMilli())
+	if err == nil {
+		return true, nil
+	}
+	// 主键冲突的错误因驱动而异，重新查询确认记录已被其他请求插入
+	if existing, getErr := s.get(ctx, s.DB, record.NotificationUUID); getErr == nil && existing != nil {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *SQLNotificationStore) Finish(ctx context.Context, notificationUUID string, err error) error {
+	status, lastError := NotificationStatusSucceeded, ""
+	if err != nil {
+		status, lastError = NotificationStatusFailed, err.Error()
+	}
+	_, execErr := s.DB.ExecContext(ctx, s.query(`UPDATE `+s.table()+` SET status = ?, last_error = ?, updated_at = ? WHERE notification_uuid = ?`),
+		string(status), lastError, time.Now().UnixMilli(), notificationUUID)
+	return execErr
+}
+
+func (s *SQLNotificationStore) Get(ctx context.Context, notificationUUID string) (*NotificationRecord, error) {
+	return s.get(ctx, s.DB, notificationUUID)
+}
+
+func (s *SQLNotificationStore) List(ctx context.Context, status NotificationStatus) ([]*NotificationRecord, error) {
+	query, args := s.selectQuery()+` ORDER BY received_at`, []any{}
+	if status != "" {
+		query, args = s.selectQuery()+` WHERE status = ? ORDER BY received_at`, []any{string(status)}
+	}
+	rows, err := s.DB.QueryContext(ctx, s.query(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var records []*NotificationRecord
+	for rows.Next() {
+		record, err := scanNotificationRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+type sqlQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (s *SQLNotificationStore) get(ctx context.Context, q sqlQueryer, notificationUUID string) (*NotificationRecord, error) {
+	row := q.QueryRowContext(ctx, s.query(s.selectQuery()+` WHERE notification_uuid = ?`), notificationUUID)
+	record, err := scanNotificationRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return record, err
+}
+
+func (s *SQLNotificationStore) selectQuery() string {
+	return `SELECT notification_uuid, notification_type, subtype, signed_payload, status, attempts, last_error, received_at, updated_at FROM ` + s.table()
+}
+
+func (s *SQLNotificationStore) table() string {
+	if s.Table == "" {
+		return "apple_notifications"
+	}
+	return s.Table
+}
+
+// query 将 ? 占位符转换为 $1、$2 ...
+func (s *SQLNotificationStore) query(query string) string {
+	if s.Placeholder != "$" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString(fmt.Sprintf("$%d", n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func scanNotificationRecord(row interface{ Scan(dest ...any) error }) (*NotificationRecord, error) {
+	record := new(NotificationRecord)
+	var status string
+	var receivedAt, updatedAt int64
+	err := row.Scan(&record.NotificationUUID, &record.NotificationType, &record.Subtype, &record.SignedPayload,
+		&status, &record.Attempts, &record.LastError, &receivedAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	record.Status = NotificationStatus(status)
+	record.ReceivedAt = time.UnixMilli(receivedAt)
+	record.UpdatedAt = time.UnixMilli(updatedAt)
+	return record, nil
+}
@@ -0,0 +1,251 @@
+package applepay
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// V1 notification_type
+// https://developer.apple.com/documentation/appstoreservernotifications/notification_type
+const (
+	NotificationV1TypeCancel                 = "CANCEL"
+	NotificationV1TypeConsumptionRequest     = "CONSUMPTION_REQUEST"
+	NotificationV1TypeDidChangeRenewalPref   = "DID_CHANGE_RENEWAL_PREF"
+	NotificationV1TypeDidChangeRenewalStatus = "DID_CHANGE_RENEWAL_STATUS"
+	NotificationV1TypeDidFailToRenew         = "DID_FAIL_TO_RENEW"
+	NotificationV1TypeDidRecover             = "DID_RECOVER"
+	NotificationV1TypeDidRenew               = "DID_RENEW"
+	NotificationV1TypeInitialBuy             = "INITIAL_BUY"
+	NotificationV1TypeInteractiveRenewal     = "INTERACTIVE_RENEWAL"
+	NotificationV1TypePriceIncreaseConsent   = "PRICE_INCREASE_CONSENT"
+	NotificationV1TypeRefund                 = "REFUND"
+	NotificationV1TypeRenewal                = "RENEWAL" // 已废弃，同 DID_RECOVER
+	NotificationV1TypeRevoke                 = "REVOKE"
+)
+
+// ErrNotificationV1Password 通知中的password与App的共享秘钥不一致
+var ErrNotificationV1Password = errors.New("notification v1 password mismatch")
+
+// NotificationV1 App Store Server Notifications V1 请求体
+// https://developer.apple.com/documentation/appstoreservernotifications/responsebodyv1
+type NotificationV1 struct {
+	AutoRenewAdamId              string          `json:"auto_renew_adam_id"`
+	AutoRenewProductId           string          `json:"auto_renew_product_id"`
+	AutoRenewStatus              string          `json:"auto_renew_status"` // true false
+	AutoRenewStatusChangeDate    string          `json:"auto_renew_status_change_date"`
+	AutoRenewStatusChangeDateMs  string          `json:"auto_renew_status_change_date_ms"`
+	AutoRenewStatusChangeDatePST string          `json:"auto_renew_status_change_date_pst"`
+	Bid                          string          `json:"bid"`
+	Bvrs                         string          `json:"bvrs"`
+	Environment                  string          `json:"environment"` // Sandbox PROD
+	ExpirationIntent             int             `json:"expiration_intent"`
+	NotificationType             string          `json:"notification_type"`
+	OriginalTransactionId        string          `json:"original_transaction_id"`
+	Password                     string          `json:"password"` // App的共享秘钥
+	UnifiedReceipt               *UnifiedReceipt `json:"unified_receipt"`
+}
+
+// UnifiedReceipt
+// https://developer.apple.com/documentation/appstoreservernotifications/unified_receipt
+type UnifiedReceipt struct {
+	Environment        string                `json:"environment"`
+	LatestReceipt      string                `json:"latest_receipt"`
+	LatestReceiptInfo  []*LatestReceiptInfo  `json:"latest_receipt_info"`
+	PendingRenewalInfo []*PendingRenewalInfo `json:"pending_renewal_info"`
+	Status             int                   `json:"status"`
+}
+
+// DecodeNotificationV1 解析V1通知，password不为空时校验通知中的共享秘钥
+func DecodeNotificationV1(body []byte, password string) (*NotificationV1, error) {
+	n := new(NotificationV1)
+	if err := json.Unmarshal(body, n); err != nil {
+		return nil, err
+	}
+	if password != "" && subtle.ConstantTimeCompare([]byte(n.Password), []byte(password)) != 1 {
+		return nil, ErrNotificationV1Password
+	}
+	if n.NotificationType == "" {
+		return nil, errors.New("notification_type is empty")
+	}
+	return n, nil
+}
+
+// LatestTransaction 返回 original_transaction_id 对应的最新一笔交易
+func (n *NotificationV1) LatestTransaction() *LatestReceiptInfo {
+	if n.UnifiedReceipt == nil {
+		return nil
+	}
+	var latest *LatestReceiptInfo
+	var latestMs int64
+	for _, item := range n.UnifiedReceipt.LatestReceiptInfo {
+		if n.OriginalTransactionId != "" && item.OriginalTransactionId != n.OriginalTransactionId {
+			continue
+		}
+		ms := parseMilliStr(item.PurchaseDateTimestamp)
+		if latest == nil || ms > latestMs {
+			latest, latestMs = item, ms
+		}
+	}
+	return latest
+}
+
+// PendingRenewal 返回 original_transaction_id 对应的续订信息
+func (n *NotificationV1) PendingRenewal() *PendingRenewalInfo {
+	if n.UnifiedReceipt == nil {
+		return nil
+	}
+	for _, item := range n.UnifiedReceipt.PendingRenewalInfo {
+		if n.OriginalTransactionId == "" || item.OriginalTransactionId == n.OriginalTransactionId {
+			return item
+		}
+	}
+	return nil
+}
+
+// NotificationTypeV2 将V1的 notification_type 转换为V2的 notificationType 与 subtype
+func (n *NotificationV1) NotificationTypeV2() (notificationType, subtype string) {
+	switch n.NotificationType {
+	case NotificationV1TypeInitialBuy:
+		return NotificationTypeSubscribed, SubtypeInitialBuy
+	case NotificationV1TypeInteractiveRenewal:
+		return NotificationTypeSubscribed, SubtypeResubscribe
+	case NotificationV1TypeDidRecover, NotificationV1TypeRenewal:
+		return NotificationTypeDidRenew, SubtypeBillingRecovery
+	case NotificationV1TypeDidFailToRenew:
+		if renewal := n.PendingRenewal(); renewal != nil && renewal.GracePeriodExpiresDateTimestamp != "" {
+			return NotificationTypeDidFailToRenew, SubtypeGracePeriod
+		}
+		return NotificationTypeDidFailToRenew, ""
+	case NotificationV1TypeDidChangeRenewalStatus:
+		if n.AutoRenewStatus == "true" {
+			return NotificationTypeDidChangeRenewalStatus, SubtypeAutoRenewEnabled
+		}
+		return NotificationTypeDidChangeRenewalStatus, SubtypeAutoRenewDisabled
+	case NotificationV1TypeCancel:
+		// CANCEL 为客服退款或升级，升级时 is_upgraded 为 true
+		if latest := n.LatestTransaction(); latest != nil && latest.IsUpgraded == "true" {
+			return NotificationTypeDidChangeRenewalPref, SubtypeUpgrade
+		}
+		return NotificationTypeRefund, ""
+	case NotificationV1TypePriceIncreaseConsent:
+		return NotificationTypePriceIncrease, SubtypePending
+	}
+	// DID_RENEW、DID_CHANGE_RENEWAL_PREF、REFUND、REVOKE、CONSUMPTION_REQUEST 与V2同名
+	return n.NotificationType, ""
+}
+
+// ToNotificationV2 转换为V2通知结构，以便V1与V2共用 NotificationV2HandlerFunc
+// V1通知没有 notificationUUID，根据通知内容生成固定的值用于去重
+func (n *NotificationV1) ToNotificationV2() *NotificationV2SignedPayloadResponse {
+	notificationType, subtype := n.NotificationTypeV2()
+	environment := n.Environment
+	if environment == "PROD" {
+		environment = "Production"
+	}
+	resp := &NotificationV2SignedPayloadResponse{
+		Payload: &NotificationV2Payload{
+			NotificationType: notificationType,
+			Subtype:          subtype,
+			NotificationUUID: n.notificationUUID(),
+			Version:          "1.0",
+			SignedDate:       n.eventDate(time.Now()),
+			Data: &Data{
+				BundleID:      n.Bid,
+				BundleVersion: n.Bvrs,
+				Environment:   environment,
+			},
+		},
+	}
+	if latest := n.LatestTransaction(); latest != nil {
+		resp.TransactionInfo = latest.toTransactionInfo(n.Bid, environment)
+	}
+	if renewal := n.PendingRenewal(); renewal != nil {
+		resp.RenewalInfo = renewal.toRenewalInfo(environment)
+	}
+	return resp
+}
+
+// eventDate V1通知没有 signedDate，使用最新交易的购买、撤销时间（续订失败时为过期时间）与自动续订状态变更时间中不晚于当前时间的最大值
+// 用于与V2通知一样按 signedDate 排序，都没有时使用接收时间
+func (n *NotificationV1) eventDate(now time.Time) int64 {
+	var date int64
+	candidates := []int64{parseMilliStr(n.AutoRenewStatusChangeDateMs)}
+	if latest := n.LatestTransaction(); latest != nil {
+		candidates = append(candidates, parseMilliStr(latest.PurchaseDateTimestamp), parseMilliStr(latest.CancellationDateTimestamp))
+		if n.NotificationType == NotificationV1TypeDidFailToRenew {
+			candidates = append(candidates, parseMilliStr(latest.ExpiresDateTimestamp))
+		}
+	}
+	for _, ms := range candidates {
+		if ms > date && ms <= now.UnixMilli() {
+			date = ms
+		}
+	}
+	if date == 0 {
+		return now.UnixMilli()
+	}
+	return date
+}
+
+func (n *NotificationV1) notificationUUID() string {
+	var transactionId string
+	if latest := n.LatestTransaction(); latest != nil {
+		transactionId = latest.TransactionId
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{n.NotificationType, n.OriginalTransactionId,
+		transactionId, n.AutoRenewStatus, n.AutoRenewStatusChangeDateMs}, "|")))
+	return "v1-" + hex.EncodeToString(sum[:16])
+}
+
+func (p *LatestReceiptInfo) toTransactionInfo(bundleId, environment string) *TransactionInfo {
+	ti := &TransactionInfo{
+		AppAccountToken:             p.AppAccountToken,
+		BundleId:                    bundleId,
+		Environment:                 environment,
+		ExpiresDate:                 parseMilliStr(p.ExpiresDateTimestamp),
+		InAppOwnershipType:          p.InAppOwnershipType,
+		IsUpgraded:                  p.IsUpgraded == "true",
+		OfferIdentifier:             p.PromotionalOfferId,
+		OriginalPurchaseDate:        parseMilliStr(p.OriginalPurchaseDateTimestamp),
+		OriginalTransactionId:       p.OriginalTransactionId,
+		ProductId:                   p.ProductId,
+		PurchaseDate:                parseMilliStr(p.PurchaseDateTimestamp),
+		RevocationDate:              parseMilliStr(p.CancellationDateTimestamp),
+		SubscriptionGroupIdentifier: p.SubscriptionGroupIdentifier,
+		TransactionId:               p.TransactionId,
+		WebOrderLineItemId:          p.WebOrderLineItemId,
+	}
+	ti.Quantity, _ = strconv.ParseInt(p.Quantity, 10, 64)
+	ti.RevocationReason, _ = strconv.Atoi(p.CancellationReason)
+	if ti.ExpiresDate > 0 {
+		ti.Type = "Auto-Renewable Subscription"
+	}
+	return ti
+}
+
+func (p *PendingRenewalInfo) toRenewalInfo(environment string) *RenewalInfo {
+	ri := &RenewalInfo{
+		AutoRenewProductId:     p.AutoRenewProductId,
+		Environment:            environment,
+		GracePeriodExpiresDate: parseMilliStr(p.GracePeriodExpiresDateTimestamp),
+		IsInBillingRetryPeriod: p.IsInBillingRetryPeriod == "1",
+		OfferIdentifier:        p.Promotionalofferid,
+		OriginalTransactionId:  p.OriginalTransactionId,
+		ProductId:              p.ProductId,
+	}
+	ri.AutoRenewStatus, _ = strconv.ParseInt(p.AutoRenewStatus, 10, 64)
+	ri.ExpirationIntent, _ = strconv.ParseInt(p.ExpirationIntent, 10, 64)
+	ri.PriceIncreaseStatus, _ = strconv.ParseInt(p.PriceConsentStatus, 10, 64)
+	return ri
+}
+
+func parseMilliStr(s string) int64 {
+	ms, _ := strconv.ParseInt(s, 10, 64)
+	return ms
+}
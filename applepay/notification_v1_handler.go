@@ -0,0 +1,88 @@
+package applepay
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// ErrNotificationV1PasswordRequired 没有设置共享秘钥，也没有设置 InsecureSkipPasswordCheck
+var ErrNotificationV1PasswordRequired = errors.New("notification v1 handler: password is required, set InsecureSkipPasswordCheck to accept unauthenticated notifications")
+
+// NotificationV1Handler App Store Server Notifications V1 的 http.Handler
+// 通知转换为V2结构后调用处理函数，可与 NotificationV2Handler 共用同一组 NotificationV2HandlerFunc
+// 200：处理成功；400：请求体无效或password不一致；405：非POST请求；413：请求体过大；500：处理函数返回错误或没有设置共享秘钥
+type NotificationV1Handler struct {
+	// Password App的共享秘钥，V1通知没有签名，共享秘钥是唯一的校验方式，为空时拒绝所有通知
+	Password string
+	// InsecureSkipPasswordCheck 为true且 Password 为空时不校验共享秘钥，任何人都可以伪造通知，仅用于测试
+	InsecureSkipPasswordCheck bool
+	Handlers                  []NotificationV2HandlerFunc
+	// MaxBodyBytes 请求体大小限制，默认 DefaultNotificationMaxBodyBytes
+	MaxBodyBytes int64
+	// ErrorLog 为nil时使用log包的默认Logger
+	ErrorLog *log.Logger
+}
+
+func NewNotificationV1Handler(password string, handlers ...NotificationV2HandlerFunc) *NotificationV1Handler {
+	return &NotificationV1Handler{Password: password, Handlers: handlers}
+}
+
+// Handle 注册处理函数
+func (h *NotificationV1Handler) Handle(fn NotificationV2HandlerFunc) *NotificationV1Handler {
+	h.Handlers = append(h.Handlers, fn)
+	return h
+}
+
+func (h *NotificationV1Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	body, status, err := readNotificationBody(w, r, h.MaxBodyBytes)
+	if err != nil {
+		h.logf("applepay: read notification v1: %v", err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if err = h.Process(r.Context(), body); err != nil {
+		var decodeErr *NotificationDecodeError
+		if errors.As(err, &decodeErr) {
+			h.logf("applepay: decode notification v1: %v", err)
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		h.logf("applepay: handle notification v1: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Process 解析并处理V1通知请求体，解析失败时返回 *NotificationDecodeError
+func (h *NotificationV1Handler) Process(ctx context.Context, body []byte) error {
+	if h.Password == "" && !h.InsecureSkipPasswordCheck {
+		return ErrNotificationV1PasswordRequired
+	}
+	n, err := DecodeNotificationV1(body, h.Password)
+	if err != nil {
+		return &NotificationDecodeError{Err: err}
+	}
+	v2 := n.ToNotificationV2()
+	for _, fn := range h.Handlers {
+		if err = fn(ctx, v2); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *NotificationV1Handler) logf(format string, args ...any) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,67 @@
+package applepay
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDecodeNotificationV1(t *testing.T) {
+	body, _ := os.ReadFile("test_notification_v1.json")
+	_, err := DecodeNotificationV1(body, "wrong")
+	assert.Equal(t, err, ErrNotificationV1Password)
+
+	n, err := DecodeNotificationV1(body, "shared-secret")
+	assert.Equal(t, err, nil)
+	v2 := n.ToNotificationV2()
+	assert.Equal(t, v2.Payload.NotificationType, NotificationTypeDidRenew)
+	assert.Equal(t, v2.Payload.Subtype, SubtypeBillingRecovery)
+	assert.Equal(t, v2.Payload.Data.Environment, "Production")
+	assert.Equal(t, v2.TransactionInfo.TransactionId, "1000000000000003")
+	assert.Equal(t, v2.TransactionInfo.ExpiresDate, int64(1702600000000))
+	assert.Equal(t, v2.RenewalInfo.AutoRenewStatus, int64(1))
+	assert.Equal(t, v2.Payload.NotificationUUID, n.ToNotificationV2().Payload.NotificationUUID)
+	assert.Equal(t, v2.Payload.SignedDate, int64(1700000000000))
+
+	// 没有任何时间时使用接收时间
+	before := time.Now().UnixMilli()
+	n.UnifiedReceipt = nil
+	assert.True(t, n.ToNotificationV2().Payload.SignedDate >= before)
+}
+
+func TestNotificationV1Handler(t *testing.T) {
+	body, _ := os.ReadFile("test_notification_v1.json")
+	var handled *NotificationV2SignedPayloadResponse
+	handler := NewNotificationV1Handler("shared-secret", func(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+		handled = n
+		return nil
+	})
+	handler.ErrorLog = log.New(io.Discard, "", 0)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, handled.TransactionInfo.OriginalTransactionId, "1000000000000001")
+
+	handler.Password = "rotated"
+	resp, _ = http.Post(server.URL, "application/json", bytes.NewReader(body))
+	assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+
+	// 没有共享秘钥时拒绝所有通知，除非显式关闭校验
+	handler.Password, handled = "", nil
+	resp, _ = http.Post(server.URL, "application/json", bytes.NewReader(body))
+	assert.Equal(t, resp.StatusCode, http.StatusInternalServerError)
+	assert.Nil(t, handled)
+	handler.InsecureSkipPasswordCheck = true
+	resp, _ = http.Post(server.URL, "application/json", bytes.NewReader(body))
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+}
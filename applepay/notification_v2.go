@@ -1,32 +1,27 @@
 package applepay
 
-import "errors"
+// NotificationV2DecodeOptions 通知解析选项
+type NotificationV2DecodeOptions struct {
+	// AllowMissing 为true时 signedRenewalInfo、signedTransactionInfo 为空不返回错误，对应字段为nil
+	// 消耗型商品的 REFUND、非订阅商品的 CONSUMPTION_REQUEST、TEST、RENEWAL_EXTENSION SUMMARY 等通知不包含续订或交易信息
+	AllowMissing bool
+}
 
 func NotificationV2SignedPayload(signedPayload string) (resp *NotificationV2SignedPayloadResponse, err error) {
-	resp = new(NotificationV2SignedPayloadResponse)
-	resp.Payload, err = DecodeSignedPayload(signedPayload)
-	if err != nil {
-		return nil, err
-	}
-	resp.RenewalInfo, err = resp.Payload.DecodeRenewalInfo()
-	if err != nil {
-		return nil, err
-	}
-	resp.TransactionInfo, err = resp.Payload.DecodeTransactionInfo()
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+	return DecodeNotificationV2(signedPayload, nil)
+}
+
+// DecodeNotificationV2 校验并解析通知，opts 为nil时缺少续订或交易信息会返回错误
+// 可以通过 resp.HasRenewalInfo()、resp.HasTransactionInfo() 等判断通知包含的内容
+func DecodeNotificationV2(signedPayload string, opts *NotificationV2DecodeOptions) (resp *NotificationV2SignedPayloadResponse, err error) {
+	return defaultSignedDataVerifier.DecodeNotificationV2(signedPayload, opts)
+}
+
+func decodeNotificationV2Payload(payload *NotificationV2Payload, opts *NotificationV2DecodeOptions) (resp *NotificationV2SignedPayloadResponse, err error) {
+	return defaultSignedDataVerifier.decodeNotificationV2Payload(payload, opts)
 }
 
 // DecodeSignedPayload 解析SignedPayload数据
 func DecodeSignedPayload(signedPayload string) (payload *NotificationV2Payload, err error) {
-	if signedPayload == "" {
-		return nil, errors.New("signedPayload is empty")
-	}
-	payload = &NotificationV2Payload{}
-	if err = ExtractClaims(signedPayload, payload); err != nil {
-		return nil, err
-	}
-	return payload, nil
+	return defaultSignedDataVerifier.DecodeSignedPayload(signedPayload)
 }
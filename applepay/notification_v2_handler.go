@@ -0,0 +1,199 @@
+package applepay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// DefaultNotificationMaxBodyBytes 通知请求体的默认大小限制
+const DefaultNotificationMaxBodyBytes int64 = 1 << 20
+
+// NotificationV2Request 苹果通知请求体
+// https://developer.apple.com/documentation/appstoreservernotifications/responsebodyv2
+type NotificationV2Request struct {
+	SignedPayload string `json:"signedPayload"`
+}
+
+// NotificationV2HandlerFunc 处理已校验并解析的通知，返回错误时响应500，苹果会重新发送该通知
+type NotificationV2HandlerFunc func(ctx context.Context, n *NotificationV2SignedPayloadResponse) error
+
+// NotificationV2Handler App Store Server Notifications V2 的 http.Handler
+// 解析请求体并校验签名后依次调用注册的处理函数
+// 200：处理成功或重复通知；400：请求体或签名无效；405：非POST请求；413：请求体过大；500：处理函数返回错误，苹果会重试
+type NotificationV2Handler struct {
+	Handlers []NotificationV2HandlerFunc
+	// Store 不为nil时按 notificationUUID 去重，已处理成功或正在处理的通知直接响应200
+	Store NotificationStore
+	// MaxBodyBytes 请求体大小限制，默认 DefaultNotificationMaxBodyBytes
+	MaxBodyBytes int64
+	// Decode 校验并解析signedPayload，默认使用 DecodeNotificationV2 并允许缺少续订或交易信息
+	Decode func(signedPayload string) (*NotificationV2SignedPayloadResponse, error)
+	// ErrorLog 为nil时使用log包的默认Logger
+	ErrorLog *log.Logger
+}
+
+func NewNotificationV2Handler(handlers ...NotificationV2HandlerFunc) *NotificationV2Handler {
+	return &NotificationV2Handler{Handlers: handlers}
+}
+
+// Handle 注册处理函数
+func (h *NotificationV2Handler) Handle(fn NotificationV2HandlerFunc) *NotificationV2Handler {
+	h.Handlers = append(h.Handlers, fn)
+	return h
+}
+
+func (h *NotificationV2Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	signedPayload, status, err := h.readSignedPayload(w, r)
+	if err != nil {
+		h.logf("applepay: read notification: %v", err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if err = h.Process(r.Context(), signedPayload); err != nil {
+		var decodeErr *NotificationDecodeError
+		if errors.As(err, &decodeErr) {
+			h.logf("applepay: decode notification: %v", err)
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		h.logf("applepay: handle notification: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// NotificationDecodeError signedPayload 校验或解析失败
+type NotificationDecodeError struct {
+	Err error
+}
+
+func (e *NotificationDecodeError) Error() string {
+	return "decode notification: " + e.Err.Error()
+}
+
+func (e *NotificationDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Process 校验并处理一条signedPayload，设置了Store时跳过重复的通知并记录处理结果
+// 校验失败时返回 *NotificationDecodeError，处理函数可以通过 SignedPayloadFromContext 获取原始signedPayload
+func (h *NotificationV2Handler) Process(ctx context.Context, signedPayload string) error {
+	decode := h.Decode
+	if decode == nil {
+		decode = decodeNotificationV2AllowMissing
+	}
+	n, err := decode(signedPayload)
+	if err != nil {
+		return &NotificationDecodeError{Err: err}
+	}
+	ctx = context.WithValue(ctx, signedPayloadContextKey{}, signedPayload)
+	if h.Store == nil || n.Payload == nil || n.Payload.NotificationUUID == "" {
+		return h.dispatch(ctx, n)
+	}
+	uuid := n.Payload.NotificationUUID
+	ok, err := h.Store.Begin(ctx, &NotificationRecord{
+		NotificationUUID: uuid,
+		NotificationType: n.Payload.NotificationType,
+		Subtype:          n.Payload.Subtype,
+		SignedPayload:    signedPayload,
+	})
+	if err != nil {
+		return fmt.Errorf("begin notification %s: %w", uuid, err)
+	}
+	if !ok {
+		return nil
+	}
+	err = h.dispatch(ctx, n)
+	if finishErr := h.Store.Finish(ctx, uuid, err); finishErr != nil {
+		h.logf("applepay: finish notification %s: %v", uuid, finishErr)
+	}
+	if err != nil {
+		return fmt.Errorf("notification %s: %w", uuid, err)
+	}
+	return nil
+}
+
+// Replay 重新处理Store中指定状态的通知，通常用于重放处理失败的通知，返回成功处理的数量
+func (h *NotificationV2Handler) Replay(ctx context.Context, status NotificationStatus) (int, error) {
+	if h.Store == nil {
+		return 0, errors.New("notification store is nil")
+	}
+	records, err := h.Store.List(ctx, status)
+	if err != nil {
+		return 0, err
+	}
+	var errs NotificationErrors
+	n := 0
+	for _, record := range records {
+		if err = h.Process(ctx, record.SignedPayload); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		n++
+	}
+	if len(errs) > 0 {
+		return n, errs
+	}
+	return n, nil
+}
+
+func (h *NotificationV2Handler) readSignedPayload(w http.ResponseWriter, r *http.Request) (string, int, error) {
+	body, status, err := readNotificationBody(w, r, h.MaxBodyBytes)
+	if err != nil {
+		return "", status, err
+	}
+	req := new(NotificationV2Request)
+	if err = json.Unmarshal(body, req); err != nil {
+		return "", http.StatusBadRequest, err
+	}
+	if req.SignedPayload == "" {
+		return "", http.StatusBadRequest, errors.New("signedPayload is empty")
+	}
+	return req.SignedPayload, http.StatusOK, nil
+}
+
+func readNotificationBody(w http.ResponseWriter, r *http.Request, maxBytes int64) ([]byte, int, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultNotificationMaxBodyBytes
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	if err != nil {
+		if int64(len(body)) >= maxBytes {
+			return nil, http.StatusRequestEntityTooLarge, err
+		}
+		return nil, http.StatusBadRequest, err
+	}
+	return body, http.StatusOK, nil
+}
+
+func (h *NotificationV2Handler) dispatch(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+	for _, fn := range h.Handlers {
+		if err := fn(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *NotificationV2Handler) logf(format string, args ...any) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func decodeNotificationV2AllowMissing(signedPayload string) (*NotificationV2SignedPayloadResponse, error) {
+	return DecodeNotificationV2(signedPayload, &NotificationV2DecodeOptions{AllowMissing: true})
+}
@@ -0,0 +1,83 @@
+package applepay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNotificationV2Handler(t *testing.T) {
+	file, _ := os.ReadFile("test_notification_v2_signed_payload.txt")
+	body, _ := json.Marshal(NotificationV2Request{SignedPayload: string(file)})
+	var handled *NotificationV2SignedPayloadResponse
+	var handleErr error
+	handler := NewNotificationV2Handler(func(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+		handled = n
+		return handleErr
+	})
+	handler.ErrorLog = log.New(io.Discard, "", 0)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(string(body)))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, handled.TransactionInfo.Environment, "Sandbox")
+
+	handleErr = errors.New("database is down")
+	resp, _ = http.Post(server.URL, "application/json", strings.NewReader(string(body)))
+	assert.Equal(t, resp.StatusCode, http.StatusInternalServerError)
+
+	resp, _ = http.Post(server.URL, "application/json", strings.NewReader(`{"signedPayload":"a.b.c"}`))
+	assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+
+	resp, _ = http.Get(server.URL)
+	assert.Equal(t, resp.StatusCode, http.StatusMethodNotAllowed)
+
+	handler.MaxBodyBytes = 1024
+	resp, _ = http.Post(server.URL, "application/json", strings.NewReader(string(body)))
+	assert.Equal(t, resp.StatusCode, http.StatusRequestEntityTooLarge)
+}
+
+func TestNotificationV2HandlerStore(t *testing.T) {
+	file, _ := os.ReadFile("test_notification_v2_signed_payload.txt")
+	store := NewMemoryNotificationStore()
+	calls := 0
+	handleErr := errors.New("database is down")
+	handler := NewNotificationV2Handler(func(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+		calls++
+		return handleErr
+	})
+	handler.Store = store
+	handler.ErrorLog = log.New(io.Discard, "", 0)
+	ctx := context.Background()
+
+	assert.NotEqual(t, handler.Process(ctx, string(file)), nil)
+	failed, _ := store.List(ctx, NotificationStatusFailed)
+	assert.Equal(t, len(failed), 1)
+	assert.Equal(t, failed[0].LastError, "database is down")
+
+	handleErr = nil
+	replayed, err := handler.Replay(ctx, NotificationStatusFailed)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, replayed, 1)
+	assert.Equal(t, calls, 2)
+
+	record, _ := store.Get(ctx, failed[0].NotificationUUID)
+	assert.Equal(t, record.Status, NotificationStatusSucceeded)
+	assert.Equal(t, record.Attempts, 2)
+
+	assert.Equal(t, handler.Process(ctx, string(file)), nil)
+	assert.Equal(t, calls, 2)
+
+	var decodeErr *NotificationDecodeError
+	assert.True(t, errors.As(handler.Process(ctx, "a.b.c"), &decodeErr))
+}
@@ -12,6 +12,31 @@ type NotificationV2SignedPayloadResponse struct {
 	TransactionInfo *TransactionInfo       `json:"transaction_info"`
 }
 
+// HasTransactionInfo 通知中是否包含交易信息，CONSUMPTION_REQUEST、REFUND 等通知可能只有交易信息
+func (r *NotificationV2SignedPayloadResponse) HasTransactionInfo() bool {
+	return r.TransactionInfo != nil
+}
+
+// HasRenewalInfo 通知中是否包含续订信息，只有自动续期订阅的通知才有
+func (r *NotificationV2SignedPayloadResponse) HasRenewalInfo() bool {
+	return r.RenewalInfo != nil
+}
+
+// HasSummary 是否为 RENEWAL_EXTENSION 的 SUMMARY 通知
+func (r *NotificationV2SignedPayloadResponse) HasSummary() bool {
+	return r.Payload != nil && r.Payload.Summary != nil
+}
+
+// HasExternalPurchaseToken 是否为 EXTERNAL_PURCHASE_TOKEN 通知
+func (r *NotificationV2SignedPayloadResponse) HasExternalPurchaseToken() bool {
+	return r.Payload != nil && r.Payload.ExternalPurchaseToken != nil
+}
+
+// HasAppData 是否为应用级别的通知
+func (r *NotificationV2SignedPayloadResponse) HasAppData() bool {
+	return r.Payload != nil && r.Payload.AppData != nil
+}
+
 // NotificationV2Payload
 //https://developer.apple.com/documentation/appstoreservernotifications/responsebodyv2decodedpayload
 type NotificationV2Payload struct {
@@ -20,7 +45,12 @@ type NotificationV2Payload struct {
 	Subtype          string `json:"subtype"`
 	NotificationUUID string `json:"notificationUUID"`
 	Version          string `json:"version"`
-	Data             *Data  `json:"data"`
+	SignedDate       int64  `json:"signedDate"` // The UNIX time, in milliseconds, that the App Store signed the JSON Web Signature data.
+	// data、summary、externalPurchaseToken、appData 同时只会出现一个
+	Data                  *Data                  `json:"data,omitempty"`
+	Summary               *Summary               `json:"summary,omitempty"`
+	ExternalPurchaseToken *ExternalPurchaseToken `json:"externalPurchaseToken,omitempty"`
+	AppData               *AppData               `json:"appData,omitempty"`
 }
 
 // Data
@@ -32,6 +62,41 @@ type Data struct {
 	Environment           string `json:"environment"`
 	SignedRenewalInfo     string `json:"signedRenewalInfo"`
 	SignedTransactionInfo string `json:"signedTransactionInfo"`
+	Status                int    `json:"status,omitempty"` // 1:有效 2:过期 3:账单重试 4:宽限期 5:撤销
+	// The reason the customer requested the refund. Only for CONSUMPTION_REQUEST notifications.
+	ConsumptionRequestReason string `json:"consumptionRequestReason,omitempty"`
+}
+
+// Summary RENEWAL_EXTENSION 通知中续订日期延长请求的汇总
+//https://developer.apple.com/documentation/appstoreservernotifications/summary
+type Summary struct {
+	RequestIdentifier      string   `json:"requestIdentifier"`
+	Environment            string   `json:"environment"`
+	AppAppleID             int      `json:"appAppleId"`
+	BundleID               string   `json:"bundleId"`
+	ProductID              string   `json:"productId"`
+	StorefrontCountryCodes []string `json:"storefrontCountryCodes"`
+	FailedCount            int64    `json:"failedCount"`
+	SucceededCount         int64    `json:"succeededCount"`
+}
+
+// ExternalPurchaseToken EXTERNAL_PURCHASE_TOKEN 通知中的外部购买token
+//https://developer.apple.com/documentation/appstoreservernotifications/externalpurchasetoken
+type ExternalPurchaseToken struct {
+	ExternalPurchaseId string `json:"externalPurchaseId"`
+	TokenCreationDate  int64  `json:"tokenCreationDate"`
+	AppAppleID         int    `json:"appAppleId"`
+	BundleID           string `json:"bundleId"`
+	TokenType          string `json:"tokenType,omitempty"` // ACQUISITION SERVICES
+}
+
+// AppData 应用级别通知的数据
+//https://developer.apple.com/documentation/appstoreservernotifications/appdata
+type AppData struct {
+	AppAppleID               int    `json:"appAppleId"`
+	BundleID                 string `json:"bundleId"`
+	Environment              string `json:"environment"`
+	SignedAppTransactionInfo string `json:"signedAppTransactionInfo,omitempty"`
 }
 
 // RenewalInfo https://developer.apple.com/documentation/appstoreservernotifications/jwsrenewalinfodecodedpayload
@@ -0,0 +1,188 @@
+package applepay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// NotificationMiddleware 包装通知的分发，可用于日志、监控、panic恢复等
+type NotificationMiddleware func(next NotificationV2HandlerFunc) NotificationV2HandlerFunc
+
+// NotificationErrors 多个处理函数返回的错误
+type NotificationErrors []error
+
+func (e NotificationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e NotificationErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationRouter 按 notificationType/subtype 分发通知
+// 同一通知匹配的所有处理函数都会被调用，错误汇总为 NotificationErrors 返回
+// Dispatch 与 NotificationV2HandlerFunc 签名一致，可以直接注册到 NotificationV2Handler
+type NotificationRouter struct {
+	routes      map[string][]NotificationV2HandlerFunc
+	fallback    []NotificationV2HandlerFunc
+	middlewares []NotificationMiddleware
+}
+
+func NewNotificationRouter() *NotificationRouter {
+	return &NotificationRouter{routes: make(map[string][]NotificationV2HandlerFunc)}
+}
+
+// OnType 注册处理函数，subtype 为空时匹配该 notificationType 的所有 subtype
+func (r *NotificationRouter) OnType(notificationType, subtype string, fn NotificationV2HandlerFunc) *NotificationRouter {
+	key := routeKey(notificationType, subtype)
+	r.routes[key] = append(r.routes[key], fn)
+	return r
+}
+
+func (r *NotificationRouter) OnSubscribed(fn NotificationV2HandlerFunc) *NotificationRouter {
+	return r.OnType(NotificationTypeSubscribed, "", fn)
+}
+
+func (r *NotificationRouter) OnDidRenew(fn NotificationV2HandlerFunc) *NotificationRouter {
+	return r.OnType(NotificationTypeDidRenew, "", fn)
+}
+
+func (r *NotificationRouter) OnDidFailToRenew(fn NotificationV2HandlerFunc) *NotificationRouter {
+	return r.OnType(NotificationTypeDidFailToRenew, "", fn)
+}
+
+func (r *NotificationRouter) OnExpired(fn NotificationV2HandlerFunc) *NotificationRouter {
+	return r.OnType(NotificationTypeExpired, "", fn)
+}
+
+func (r *NotificationRouter) OnRefund(fn NotificationV2HandlerFunc) *NotificationRouter {
+	return r.OnType(NotificationTypeRefund, "", fn)
+}
+
+func (r *NotificationRouter) OnRevoke(fn NotificationV2HandlerFunc) *NotificationRouter {
+	return r.OnType(NotificationTypeRevoke, "", fn)
+}
+
+func (r *NotificationRouter) OnConsumptionRequest(fn NotificationV2HandlerFunc) *NotificationRouter {
+	return r.OnType(NotificationTypeConsumptionRequest, "", fn)
+}
+
+func (r *NotificationRouter) OnExternalPurchaseToken(fn NotificationV2HandlerFunc) *NotificationRouter {
+	return r.OnType(NotificationTypeExternalPurchaseToken, "", fn)
+}
+
+func (r *NotificationRouter) OnTest(fn NotificationV2HandlerFunc) *NotificationRouter {
+	return r.OnType(NotificationTypeTest, "", fn)
+}
+
+// Fallback 注册没有匹配到任何处理函数时调用的处理函数
+func (r *NotificationRouter) Fallback(fn NotificationV2HandlerFunc) *NotificationRouter {
+	r.fallback = append(r.fallback, fn)
+	return r
+}
+
+// Use 注册中间件，先注册的在最外层
+func (r *NotificationRouter) Use(middlewares ...NotificationMiddleware) *NotificationRouter {
+	r.middlewares = append(r.middlewares, middlewares...)
+	return r
+}
+
+// Dispatch 分发一条通知
+func (r *NotificationRouter) Dispatch(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+	next := r.dispatch
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		next = r.middlewares[i](next)
+	}
+	return next(ctx, n)
+}
+
+func (r *NotificationRouter) dispatch(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+	if n == nil || n.Payload == nil {
+		return errors.New("notification payload is nil")
+	}
+	var handlers []NotificationV2HandlerFunc
+	if n.Payload.Subtype != "" {
+		handlers = append(handlers, r.routes[routeKey(n.Payload.NotificationType, n.Payload.Subtype)]...)
+	}
+	handlers = append(handlers, r.routes[routeKey(n.Payload.NotificationType, "")]...)
+	if len(handlers) == 0 {
+		handlers = r.fallback
+	}
+	var errs NotificationErrors
+	for _, fn := range handlers {
+		if err := fn(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func routeKey(notificationType, subtype string) string {
+	if subtype == "" {
+		return notificationType
+	}
+	return notificationType + "/" + subtype
+}
+
+// RecoverMiddleware 将处理函数中的panic转换为错误
+func RecoverMiddleware() NotificationMiddleware {
+	return func(next NotificationV2HandlerFunc) NotificationV2HandlerFunc {
+		return func(ctx context.Context, n *NotificationV2SignedPayloadResponse) (err error) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					err = fmt.Errorf("panic: %v\n%s", rec, debug.Stack())
+				}
+			}()
+			return next(ctx, n)
+		}
+	}
+}
+
+// LoggingMiddleware 记录每条通知的类型、耗时与错误，logger 为nil时使用log包的默认Logger
+func LoggingMiddleware(logger *log.Logger) NotificationMiddleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next NotificationV2HandlerFunc) NotificationV2HandlerFunc {
+		return func(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+			start := time.Now()
+			err := next(ctx, n)
+			if n != nil && n.Payload != nil {
+				logger.Printf("applepay: notification %s %s uuid=%s duration=%s err=%v",
+					n.Payload.NotificationType, n.Payload.Subtype, n.Payload.NotificationUUID, time.Since(start), err)
+			}
+			return err
+		}
+	}
+}
+
+// MetricsMiddleware 每条通知处理完成后回调，用于上报监控指标
+func MetricsMiddleware(observe func(notificationType, subtype string, duration time.Duration, err error)) NotificationMiddleware {
+	return func(next NotificationV2HandlerFunc) NotificationV2HandlerFunc {
+		return func(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+			start := time.Now()
+			err := next(ctx, n)
+			if n != nil && n.Payload != nil {
+				observe(n.Payload.NotificationType, n.Payload.Subtype, time.Since(start), err)
+			}
+			return err
+		}
+	}
+}
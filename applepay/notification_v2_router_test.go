@@ -0,0 +1,51 @@
+package applepay
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNotificationRouter(t *testing.T) {
+	var calls []string
+	record := func(name string, err error) NotificationV2HandlerFunc {
+		return func(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+	errRefund := errors.New("refund failed")
+	router := NewNotificationRouter().
+		Use(RecoverMiddleware()).
+		OnType(NotificationTypeSubscribed, SubtypeInitialBuy, record("initial", nil)).
+		OnSubscribed(record("subscribed", nil)).
+		OnRefund(record("refund1", errRefund)).
+		OnRefund(record("refund2", nil)).
+		OnTest(func(ctx context.Context, n *NotificationV2SignedPayloadResponse) error {
+			panic("boom")
+		}).
+		Fallback(record("fallback", nil))
+	notification := func(notificationType, subtype string) *NotificationV2SignedPayloadResponse {
+		return &NotificationV2SignedPayloadResponse{Payload: &NotificationV2Payload{NotificationType: notificationType, Subtype: subtype}}
+	}
+	ctx := context.Background()
+
+	assert.Equal(t, router.Dispatch(ctx, notification(NotificationTypeSubscribed, SubtypeInitialBuy)), nil)
+	assert.Equal(t, calls, []string{"initial", "subscribed"})
+
+	calls = nil
+	assert.Equal(t, router.Dispatch(ctx, notification(NotificationTypeSubscribed, SubtypeResubscribe)), nil)
+	assert.Equal(t, calls, []string{"subscribed"})
+
+	calls = nil
+	err := router.Dispatch(ctx, notification(NotificationTypeRefund, ""))
+	assert.True(t, errors.Is(err, errRefund))
+	assert.Equal(t, calls, []string{"refund1", "refund2"})
+
+	calls = nil
+	assert.Equal(t, router.Dispatch(ctx, notification(NotificationTypeMetadataUpdate, "")), nil)
+	assert.Equal(t, calls, []string{"fallback"})
+
+	assert.NotEqual(t, router.Dispatch(ctx, notification(NotificationTypeTest, "")), nil)
+}
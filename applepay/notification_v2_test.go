@@ -1,6 +1,7 @@
 package applepay
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
@@ -17,3 +18,36 @@ func TestDecodeSignedPayload(t *testing.T) {
 	assert.Equal(t, err, nil)
 	assert.Equal(t, transactionInfo.Environment, "Sandbox")
 }
+
+func TestDecodeNotificationV2AllowMissing(t *testing.T) {
+	tests := []struct {
+		file            string
+		wantTransaction bool
+		wantRenewal     bool
+		wantSummary     bool
+		wantToken       bool
+		wantAppData     bool
+	}{
+		{file: "test_notification_v2_refund.json", wantTransaction: true},
+		{file: "test_notification_v2_test.json"},
+		{file: "test_notification_v2_summary.json", wantSummary: true},
+		{file: "test_notification_v2_external_purchase_token.json", wantToken: true},
+		{file: "test_notification_v2_app_data.json", wantAppData: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			file, _ := os.ReadFile(tt.file)
+			payload := new(NotificationV2Payload)
+			assert.Equal(t, json.Unmarshal(file, payload), nil)
+			_, err := decodeNotificationV2Payload(payload, nil)
+			assert.NotEqual(t, err, nil)
+			resp, err := decodeNotificationV2Payload(payload, &NotificationV2DecodeOptions{AllowMissing: true})
+			assert.Equal(t, err, nil)
+			assert.Equal(t, resp.HasTransactionInfo(), tt.wantTransaction)
+			assert.Equal(t, resp.HasRenewalInfo(), tt.wantRenewal)
+			assert.Equal(t, resp.HasSummary(), tt.wantSummary)
+			assert.Equal(t, resp.HasExternalPurchaseToken(), tt.wantToken)
+			assert.Equal(t, resp.HasAppData(), tt.wantAppData)
+		})
+	}
+}
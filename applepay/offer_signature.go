@@ -0,0 +1,116 @@
+package applepay
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt"
+	"github.com/pkg6/applego/jwt"
+	"github.com/pkg6/applego/utility"
+	"time"
+)
+
+const (
+	// OfferAudiencePromotionalOffer 促销优惠 JWS 签名的 aud
+	OfferAudiencePromotionalOffer = "promotional-offer"
+	// OfferAudienceIntroductoryOfferEligibility 首次优惠资格 JWS 签名的 aud
+	OfferAudienceIntroductoryOfferEligibility = "introductory-offer-eligibility"
+)
+
+// OfferSignatureClaims StoreKit 优惠 JWS 签名的 payload
+// https://developer.apple.com/documentation/storekit/generating-jws-to-sign-app-store-requests
+type OfferSignatureClaims struct {
+	jwt2.StandardClaims
+	Bid                    string `json:"bid"`
+	Nonce                  string `json:"nonce"`
+	ProductId              string `json:"productId,omitempty"`
+	OfferIdentifier        string `json:"offerIdentifier,omitempty"`
+	TransactionId          string `json:"transactionId,omitempty"`
+	AllowIntroductoryOffer *bool  `json:"allowIntroductoryOffer,omitempty"`
+	// Request base64编码的请求内容，用于 Advanced Commerce API 的应用内请求
+	Request string `json:"request,omitempty"`
+}
+
+// OfferSigner 生成 StoreKit 所需的 JWS 格式优惠签名，使用 In-App Purchase 秘钥
+// 赢回优惠(win-back offer)由 StoreKit 直接展示，不需要服务端签名
+type OfferSigner struct {
+	PrivateKey *ecdsa.PrivateKey
+	// Signer 不为nil时优先于 PrivateKey，可以是 HSM/KMS 的签名器
+	Signer crypto.Signer
+	// KeyID In-App Purchase 秘钥的ID
+	KeyID string
+	// IssuerID App Store Connect 中的 issuer ID
+	IssuerID string
+	BundleID string
+}
+
+func NewOfferSigner(privateKey []byte, keyID, issuerID, bundleID string) (*OfferSigner, error) {
+	key, err := utility.EcdsaPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &OfferSigner{PrivateKey: key, KeyID: keyID, IssuerID: issuerID, BundleID: bundleID}, nil
+}
+
+// NewOfferSignerWithSigner 使用 crypto.Signer 生成优惠签名
+func NewOfferSignerWithSigner(signer crypto.Signer, keyID, issuerID, bundleID string) *OfferSigner {
+	return &OfferSigner{Signer: signer, KeyID: keyID, IssuerID: issuerID, BundleID: bundleID}
+}
+
+// OfferSigner 使用 ApiClient 的秘钥生成优惠签名
+func (a *ApiClient) OfferSigner() *OfferSigner {
+	return &OfferSigner{PrivateKey: a.PrivateKey, Signer: a.Signer, KeyID: a.KeyID, IssuerID: a.Iss, BundleID: a.Bid}
+}
+
+// SignPromotionalOffer 生成促销优惠签名，用于 Product.PurchaseOption.promotionalOffer(_:compactJWS:)
+// transactionId 可以为空，传入时为该用户任意一笔交易的ID
+func (s *OfferSigner) SignPromotionalOffer(productId, offerIdentifier, transactionId string) (string, error) {
+	if productId == "" || offerIdentifier == "" {
+		return "", errors.New("productId and offerIdentifier are required")
+	}
+	return s.Sign(OfferAudiencePromotionalOffer, &OfferSignatureClaims{
+		ProductId:       productId,
+		OfferIdentifier: offerIdentifier,
+		TransactionId:   transactionId,
+	})
+}
+
+// SignIntroductoryOfferEligibility 生成首次优惠资格签名，用于 Product.PurchaseOption.introductoryOfferEligibility(compactJWS:)
+func (s *OfferSigner) SignIntroductoryOfferEligibility(productId string, allowIntroductoryOffer bool, transactionId string) (string, error) {
+	if productId == "" || transactionId == "" {
+		return "", errors.New("productId and transactionId are required")
+	}
+	return s.Sign(OfferAudienceIntroductoryOfferEligibility, &OfferSignatureClaims{
+		ProductId:              productId,
+		TransactionId:          transactionId,
+		AllowIntroductoryOffer: &allowIntroductoryOffer,
+	})
+}
+
+// Sign 填充 iss、iat、aud、bid 与 nonce 后签名，claims 中已有的 nonce 不会被覆盖
+func (s *OfferSigner) Sign(audience string, claims *OfferSignatureClaims) (string, error) {
+	signer := s.Signer
+	if signer == nil {
+		if s.PrivateKey == nil {
+			return "", errors.New("offer signer private key is nil")
+		}
+		signer = s.PrivateKey
+	}
+	c := *claims
+	c.Issuer = s.IssuerID
+	c.IssuedAt = time.Now().Unix()
+	c.Audience = audience
+	c.Bid = s.BundleID
+	if c.Nonce == "" {
+		nonce, err := NewPromotionalOfferNonce()
+		if err != nil {
+			return "", err
+		}
+		c.Nonce = nonce
+	}
+	return jwt.EncodeWithSigner(signer, &c, map[string]any{
+		"alg": "ES256",
+		"kid": s.KeyID,
+		"typ": "JWT",
+	})
+}
@@ -0,0 +1,43 @@
+package applepay
+
+import (
+	jwt2 "github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestOfferSigner(t *testing.T) {
+	signer, err := NewOfferSigner([]byte(testOfferKey), "ABCDEF1234", "57246542-96fe-1a63-e053-0824d011072a", "com.example.app")
+	assert.Equal(t, err, nil)
+	parse := func(token string) (*jwt2.Token, *OfferSignatureClaims) {
+		claims := new(OfferSignatureClaims)
+		parsed, err := jwt2.ParseWithClaims(token, claims, func(token *jwt2.Token) (interface{}, error) {
+			return &signer.PrivateKey.PublicKey, nil
+		})
+		assert.Equal(t, err, nil)
+		return parsed, claims
+	}
+
+	token, err := signer.SignPromotionalOffer("com.example.monthly", "winback_50", "2000000000000001")
+	assert.Equal(t, err, nil)
+	parsed, claims := parse(token)
+	assert.Equal(t, parsed.Header["kid"], "ABCDEF1234")
+	assert.Equal(t, parsed.Header["alg"], "ES256")
+	assert.Equal(t, claims.Audience, OfferAudiencePromotionalOffer)
+	assert.Equal(t, claims.Issuer, "57246542-96fe-1a63-e053-0824d011072a")
+	assert.Equal(t, claims.Bid, "com.example.app")
+	assert.Equal(t, claims.ProductId, "com.example.monthly")
+	assert.Equal(t, claims.OfferIdentifier, "winback_50")
+	assert.Equal(t, claims.TransactionId, "2000000000000001")
+	assert.Equal(t, len(claims.Nonce), 36)
+	assert.Equal(t, claims.AllowIntroductoryOffer, (*bool)(nil))
+
+	token, err = signer.SignIntroductoryOfferEligibility("com.example.monthly", false, "2000000000000001")
+	assert.Equal(t, err, nil)
+	_, claims = parse(token)
+	assert.Equal(t, claims.Audience, OfferAudienceIntroductoryOfferEligibility)
+	assert.Equal(t, *claims.AllowIntroductoryOffer, false)
+
+	_, err = signer.SignIntroductoryOfferEligibility("com.example.monthly", true, "")
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,123 @@
+package applepay
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/pkg6/applego/utility"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// promotionalOfferSeparator 签名内容各字段之间的分隔符 U+2063
+const promotionalOfferSeparator = "\u2063"
+
+// ErrPromotionalOfferSignature 促销优惠签名校验失败
+var ErrPromotionalOfferSignature = errors.New("promotional offer signature verification failed")
+
+// PromotionalOfferRequest 生成促销优惠签名的参数
+// https://developer.apple.com/documentation/storekit/in-app_purchase/original_api_for_in-app_purchase/subscriptions_and_offers/generating_a_signature_for_promotional_offers
+type PromotionalOfferRequest struct {
+	BundleId string
+	// KeyIdentifier App Store Connect 中 In-App Purchase 秘钥的ID
+	KeyIdentifier     string
+	ProductIdentifier string
+	// OfferIdentifier App Store Connect 中配置的促销优惠ID
+	OfferIdentifier string
+	// AppAccountToken 即 applicationUsername，可以为空
+	AppAccountToken string
+	// Nonce UUID，为空时自动生成
+	Nonce string
+	// Timestamp 毫秒时间戳，为0时使用当前时间
+	Timestamp int64
+}
+
+// PromotionalOfferSignature 返回给客户端用于构造 SKPaymentDiscount
+type PromotionalOfferSignature struct {
+	KeyIdentifier string `json:"keyIdentifier"`
+	Nonce         string `json:"nonce"`
+	Timestamp     int64  `json:"timestamp"`
+	Signature     string `json:"signature"`
+}
+
+// PromotionalOfferPayload 待签名内容，appAccountToken 与 nonce 使用小写
+func PromotionalOfferPayload(req *PromotionalOfferRequest) string {
+	return strings.Join([]string{
+		req.BundleId,
+		req.KeyIdentifier,
+		req.ProductIdentifier,
+		req.OfferIdentifier,
+		strings.ToLower(req.AppAccountToken),
+		strings.ToLower(req.Nonce),
+		strconv.FormatInt(req.Timestamp, 10),
+	}, promotionalOfferSeparator)
+}
+
+// SignPromotionalOffer 使用 In-App Purchase 秘钥(p8)生成促销优惠签名
+func SignPromotionalOffer(privateKey []byte, req *PromotionalOfferRequest) (*PromotionalOfferSignature, error) {
+	key, err := utility.EcdsaPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return SignPromotionalOfferWithKey(key, req)
+}
+
+// SignPromotionalOfferWithKey 生成促销优惠签名，签名为 SHA256 ECDSA 的 ASN.1 DER 编码再 base64
+func SignPromotionalOfferWithKey(key *ecdsa.PrivateKey, req *PromotionalOfferRequest) (*PromotionalOfferSignature, error) {
+	if key == nil {
+		return nil, errors.New("private key is nil")
+	}
+	return SignPromotionalOfferWithSigner(key, req)
+}
+
+// SignPromotionalOfferWithSigner 使用 crypto.Signer 生成促销优惠签名，签名器需返回 ASN.1 DER 编码的ECDSA签名
+func SignPromotionalOfferWithSigner(signer crypto.Signer, req *PromotionalOfferRequest) (*PromotionalOfferSignature, error) {
+	if req.BundleId == "" || req.KeyIdentifier == "" || req.ProductIdentifier == "" || req.OfferIdentifier == "" {
+		return nil, errors.New("bundleId, keyIdentifier, productIdentifier and offerIdentifier are required")
+	}
+	r := *req
+	if r.Nonce == "" {
+		nonce, err := NewPromotionalOfferNonce()
+		if err != nil {
+			return nil, err
+		}
+		r.Nonce = nonce
+	}
+	if r.Timestamp == 0 {
+		r.Timestamp = time.Now().UnixMilli()
+	}
+	digest := sha256.Sum256([]byte(PromotionalOfferPayload(&r)))
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	return &PromotionalOfferSignature{
+		KeyIdentifier: r.KeyIdentifier,
+		Nonce:         strings.ToLower(r.Nonce),
+		Timestamp:     r.Timestamp,
+		Signature:     base64.StdEncoding.EncodeToString(signature),
+	}, nil
+}
+
+// VerifyPromotionalOffer 使用公钥校验促销优惠签名
+func VerifyPromotionalOffer(publicKey *ecdsa.PublicKey, req *PromotionalOfferRequest, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+	digest := sha256.Sum256([]byte(PromotionalOfferPayload(req)))
+	if !ecdsa.VerifyASN1(publicKey, digest[:], sig) {
+		return ErrPromotionalOfferSignature
+	}
+	return nil
+}
+
+// NewPromotionalOfferNonce 生成小写的 UUID v4
+func NewPromotionalOfferNonce() (string, error) {
+	return utility.NewUUID()
+}
@@ -0,0 +1,191 @@
+package applepay
+
+import (
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/pkg6/applego/utility"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// app receipt 字段类型
+// https://developer.apple.com/library/archive/releasenotes/General/ValidateAppStoreReceipt/Chapters/ReceiptFields.html
+const (
+	receiptFieldReceiptType                = 0
+	receiptFieldAppItemId                  = 1
+	receiptFieldBundleId                   = 2
+	receiptFieldApplicationVersion         = 3
+	receiptFieldCreationDate               = 12
+	receiptFieldInApp                      = 17
+	receiptFieldOriginalPurchaseDate       = 18
+	receiptFieldOriginalApplicationVersion = 19
+	receiptFieldExpirationDate             = 21
+
+	inAppFieldQuantity              = 1701
+	inAppFieldProductId             = 1702
+	inAppFieldTransactionId         = 1703
+	inAppFieldPurchaseDate          = 1704
+	inAppFieldOriginalTransactionId = 1705
+	inAppFieldOriginalPurchaseDate  = 1706
+	inAppFieldExpiresDate           = 1708
+	inAppFieldWebOrderLineItemId    = 1711
+	inAppFieldCancellationDate      = 1712
+	inAppFieldIsTrialPeriod         = 1713
+	inAppFieldIsInIntroOfferPeriod  = 1719
+	inAppFieldPromotionalOfferId    = 1721
+)
+
+type receiptAttribute struct {
+	Type    int
+	Version int
+	Value   []byte
+}
+
+// ParseReceipt 离线解析app receipt，不依赖 verifyReceipt 接口
+// receipt：app上传的base64编码的PKCS#7票据
+// 会校验票据签名以及证书链是否由苹果根证书签发，由于苹果签名证书会过期，证书链以票据生成时间为准进行校验
+// 文档：https://developer.apple.com/documentation/appstorereceipts/validating_receipts_on_the_device
+func ParseReceipt(receipt string) (*Receipt, error) {
+	if receipt == "" {
+		return nil, errors.New("receipt is empty")
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(receipt))
+	if err != nil {
+		return nil, err
+	}
+	return ParseReceiptBytes(der)
+}
+
+// ParseReceiptBytes 解析DER格式的app receipt，参考 ParseReceipt
+func ParseReceiptBytes(der []byte) (*Receipt, error) {
+	var receipt *Receipt
+	_, err := pkcs7Verify(der, []byte(receiptRootPEM), func(content []byte) (t time.Time, err error) {
+		if receipt, err = decodeReceipt(content); err != nil {
+			return
+		}
+		if receipt.ReceiptCreationDateTimestamp == "" {
+			return t, errors.New("receipt creation date is empty")
+		}
+		return utility.MilliStrToTime(receipt.ReceiptCreationDateTimestamp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+func decodeReceipt(content []byte) (*Receipt, error) {
+	var attrs []receiptAttribute
+	if _, err := asn1.UnmarshalWithParams(content, &attrs, "set"); err != nil {
+		return nil, fmt.Errorf("receipt: %w", err)
+	}
+	receipt := new(Receipt)
+	for _, attr := range attrs {
+		var err error
+		switch attr.Type {
+		case receiptFieldReceiptType:
+			receipt.ReceiptType, err = receiptString(attr.Value)
+		case receiptFieldAppItemId:
+			receipt.AppItemId, err = receiptInt(attr.Value)
+			receipt.AdamId = receipt.AppItemId
+		case receiptFieldBundleId:
+			receipt.BundleId, err = receiptString(attr.Value)
+		case receiptFieldApplicationVersion:
+			receipt.ApplicationVersion, err = receiptString(attr.Value)
+		case receiptFieldOriginalApplicationVersion:
+			receipt.OriginalApplicationVersion, err = receiptString(attr.Value)
+		case receiptFieldCreationDate:
+			receipt.ReceiptCreationDate, receipt.ReceiptCreationDateTimestamp, receipt.ReceiptCreationDatePST, err = receiptDate(attr.Value)
+		case receiptFieldOriginalPurchaseDate:
+			receipt.OriginalPurchaseDate, receipt.OriginalPurchaseDateTimestamp, receipt.OriginalPurchaseDatePST, err = receiptDate(attr.Value)
+		case receiptFieldExpirationDate:
+			receipt.ExpirationDate, receipt.ExpirationDateTimestamp, receipt.ExpirationDatePST, err = receiptDate(attr.Value)
+		case receiptFieldInApp:
+			var inApp *InApp
+			if inApp, err = decodeReceiptInApp(attr.Value); err == nil {
+				receipt.InApp = append(receipt.InApp, inApp)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("receipt: field %d: %w", attr.Type, err)
+		}
+	}
+	return receipt, nil
+}
+
+func decodeReceiptInApp(value []byte) (*InApp, error) {
+	var attrs []receiptAttribute
+	if _, err := asn1.UnmarshalWithParams(value, &attrs, "set"); err != nil {
+		return nil, err
+	}
+	inApp := new(InApp)
+	for _, attr := range attrs {
+		var err error
+		var n int64
+		switch attr.Type {
+		case inAppFieldQuantity:
+			n, err = receiptInt(attr.Value)
+			inApp.Quantity = strconv.FormatInt(n, 10)
+		case inAppFieldProductId:
+			inApp.ProductId, err = receiptString(attr.Value)
+		case inAppFieldTransactionId:
+			inApp.TransactionId, err = receiptString(attr.Value)
+		case inAppFieldOriginalTransactionId:
+			inApp.OriginalTransactionId, err = receiptString(attr.Value)
+		case inAppFieldPromotionalOfferId:
+			inApp.PromotionalOfferId, err = receiptString(attr.Value)
+		case inAppFieldWebOrderLineItemId:
+			n, err = receiptInt(attr.Value)
+			inApp.WebOrderLineItemId = strconv.FormatInt(n, 10)
+		case inAppFieldIsTrialPeriod:
+			n, err = receiptInt(attr.Value)
+			inApp.IsTrialPeriod = strconv.FormatBool(n == 1)
+		case inAppFieldIsInIntroOfferPeriod:
+			n, err = receiptInt(attr.Value)
+			inApp.IsInIntroOfferPeriod = strconv.FormatBool(n == 1)
+		case inAppFieldPurchaseDate:
+			inApp.PurchaseDate, inApp.PurchaseDateTimestamp, inApp.PurchaseDatePST, err = receiptDate(attr.Value)
+		case inAppFieldOriginalPurchaseDate:
+			inApp.OriginalPurchaseDate, inApp.OriginalPurchaseDateTimestamp, inApp.OriginalPurchaseDatePST, err = receiptDate(attr.Value)
+		case inAppFieldExpiresDate:
+			inApp.ExpiresDate, inApp.ExpiresDateTimestamp, inApp.ExpiresDatePST, err = receiptDate(attr.Value)
+		case inAppFieldCancellationDate:
+			inApp.CancellationDate, inApp.CancellationDateTimestamp, inApp.CancellationDatePST, err = receiptDate(attr.Value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("in_app field %d: %w", attr.Type, err)
+		}
+	}
+	return inApp, nil
+}
+
+func receiptString(value []byte) (s string, err error) {
+	_, err = asn1.Unmarshal(value, &s)
+	return
+}
+
+func receiptInt(value []byte) (n int64, err error) {
+	_, err = asn1.Unmarshal(value, &n)
+	return
+}
+
+// receiptDate 票据中的时间为RFC 3339格式，转换为与 verifyReceipt 响应一致的三种格式
+func receiptDate(value []byte) (date, timestamp, pst string, err error) {
+	var s string
+	if s, err = receiptString(value); err != nil || s == "" {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return
+	}
+	date = t.UTC().Format("2006-01-02 15:04:05") + " Etc/GMT"
+	timestamp = strconv.FormatInt(t.UnixMilli(), 10)
+	if loc, e := time.LoadLocation("America/Los_Angeles"); e == nil {
+		pst = t.In(loc).Format("2006-01-02 15:04:05") + " America/Los_Angeles"
+	}
+	return
+}
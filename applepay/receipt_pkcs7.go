@@ -0,0 +1,219 @@
+package applepay
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// https://www.apple.com/certificateauthority/
+// https://www.apple.com/appleca/AppleIncRootCertificate.cer
+// app receipt 由 Apple Inc. Root(RSA) 签发，与 JWS 使用的 G3 根证书不同
+const receiptRootPEM = `
+-----BEGIN CERTIFICATE-----
+MIIEuzCCA6OgAwIBAgIBAjANBgkqhkiG9w0BAQUFADBiMQswCQYDVQQGEwJVUzET
+MBEGA1UEChMKQXBwbGUgSW5jLjEmMCQGA1UECxMdQXBwbGUgQ2VydGlmaWNhdGlv
+biBBdXRob3JpdHkxFjAUBgNVBAMTDUFwcGxlIFJvb3QgQ0EwHhcNMDYwNDI1MjE0
+MDM2WhcNMzUwMjA5MjE0MDM2WjBiMQswCQYDVQQGEwJVUzETMBEGA1UEChMKQXBw
+bGUgSW5jLjEmMCQGA1UECxMdQXBwbGUgQ2VydGlmaWNhdGlvbiBBdXRob3JpdHkx
+FjAUBgNVBAMTDUFwcGxlIFJvb3QgQ0EwggEiMA0GCSqGSIb3DQEBAQUAA4IBDwAw
+ggEKAoIBAQDkkakJH5HbHkdQ6wXtXnmELes2oldMVeyLGYne+Uts9QerIjAC6Bg+
++FAJ039BqJj50cpmnCRrEdCju+QbKsMflZ56DKRHi1vUFjczy8QPTc4UadHJGXL1
+XQ7Vf1+b8iUDulWPTV0N8WQ1IxVLFVkds5T39pyez1C6wVhQZ48ItCD3y6wsIG9w
+tj8BMIy3Q88PnT3zK0koGsj+zrW5DtleHNbLPbU6rfQPDgCSC7EhFi501TwN22IW
+q6NxkkdTVcGvL0Gz+PvjcM3mo0xFfh9Ma1CWQYnEdGILEINBhzOKgbEwWOxaBDKM
+aLOPHd5lc/9nXmW8Sdh2nzMUZaF3lMktAgMBAAGjggF6MIIBdjAOBgNVHQ8BAf8E
+BAMCAQYwDwYDVR0TAQH/BAUwAwEB/zAdBgNVHQ4EFgQUK9BpR5R2Cf70a40uQKb3
+R01/CF4wHwYDVR0jBBgwFoAUK9BpR5R2Cf70a40uQKb3R01/CF4wggERBgNVHSAE
+ggEIMIIBBDCCAQAGCSqGSIb3Y2QFATCB8jAqBggrBgEFBQcCARYeaHR0cHM6Ly93
+d3cuYXBwbGUuY29tL2FwcGxlY2EvMIHDBggrBgEFBQcCAjCBthqBs1JlbGlhbmNl
+IG9uIHRoaXMgY2VydGlmaWNhdGUgYnkgYW55IHBhcnR5IGFzc3VtZXMgYWNjZXB0
+YW5jZSBvZiB0aGUgdGhlbiBhcHBsaWNhYmxlIHN0YW5kYXJkIHRlcm1zIGFuZCBj
+b25kaXRpb25zIG9mIHVzZSwgY2VydGlmaWNhdGUgcG9saWN5IGFuZCBjZXJ0aWZp
+Y2F0aW9uIHByYWN0aWNlIHN0YXRlbWVudHMuMA0GCSqGSIb3DQEBBQUAA4IBAQBc
+NplMLXi37Yyb3PN3m/J20ncwT8EfhYOFG5k9RzfyqZtAjizUsZAS2L70c5vu0mQP
+y3lPNNiiPvl4/2vIB+x9OYOLUyDTOMSxv5pPCmv/K/xZpwUJfBdAVhEedNO3iyM7
+R6PVbyTi69G3cN8PReEnyvFteO3ntRcXqNx+IjXKJdXZD9Zr1KIkIxH3oayPc4Fg
+xhtbCS+SsvhESPBgOJ4V9T0mZyCKM2r3DYLP3uujL/lTaltkwGMzd/c6ByxW69oP
+IQ7aunMZT7XZNn/Bh1XZp5m5MkL72NVxnn6hUrcbvZNCJBIqxw8dtk2cXmPIS4AX
+UKqK1drk/NAJBzewdXUh
+-----END CERTIFICATE-----
+`
+
+var (
+	oidPKCS7Data           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidPKCS7SignedData     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidAttrMessageDigest   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidDigestSHA1          = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidDigestSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidEncryptionRSA       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidEncryptionRSASHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+	oidEncryptionRSASHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+)
+
+// https://datatracker.ietf.org/doc/html/rfc2315
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      pkcs7ContentInfo
+	Certificates     asn1.RawValue     `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue     `asn1:"optional,tag:1"`
+	SignerInfos      []pkcs7SignerInfo `asn1:"set"`
+}
+
+type pkcs7IssuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type pkcs7SignerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     pkcs7IssuerAndSerial
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type pkcs7Attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// pkcs7Verify 解析PKCS#7 signedData，校验签名以及证书链，返回签名的原始内容
+// signingTime：证书链的校验时间，苹果的签名证书会过期，需要以票据生成时间校验
+func pkcs7Verify(der []byte, rootPEM []byte, signingTime func(content []byte) (time.Time, error)) ([]byte, error) {
+	var info pkcs7ContentInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("pkcs7: %w", err)
+	} else if len(rest) > 0 {
+		return nil, errors.New("pkcs7: trailing data")
+	}
+	if !info.ContentType.Equal(oidPKCS7SignedData) {
+		return nil, errors.New("pkcs7: content is not signedData")
+	}
+	var sd pkcs7SignedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("pkcs7: %w", err)
+	}
+	if !sd.ContentInfo.ContentType.Equal(oidPKCS7Data) {
+		return nil, errors.New("pkcs7: signed content is not data")
+	}
+	var content []byte
+	if _, err := asn1.Unmarshal(sd.ContentInfo.Content.Bytes, &content); err != nil {
+		return nil, fmt.Errorf("pkcs7: %w", err)
+	}
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("pkcs7: %w", err)
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("pkcs7: expected one signer, got %d", len(sd.SignerInfos))
+	}
+	signer := sd.SignerInfos[0]
+	var signerCert *x509.Certificate
+	for _, cert := range certs {
+		if cert.SerialNumber.Cmp(signer.IssuerAndSerialNumber.SerialNumber) == 0 &&
+			bytes.Equal(cert.RawIssuer, signer.IssuerAndSerialNumber.Issuer.FullBytes) {
+			signerCert = cert
+			break
+		}
+	}
+	if signerCert == nil {
+		return nil, errors.New("pkcs7: signer certificate not found")
+	}
+	if err = pkcs7VerifySignature(signerCert, signer, content); err != nil {
+		return nil, err
+	}
+	verifyTime, err := signingTime(content)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(rootPEM) {
+		return nil, errors.New("failed to parse root certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs {
+		if cert != signerCert {
+			intermediates.AddCert(cert)
+		}
+	}
+	_, err = signerCert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   verifyTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func pkcs7VerifySignature(cert *x509.Certificate, signer pkcs7SignerInfo, content []byte) error {
+	var hash crypto.Hash
+	var algo x509.SignatureAlgorithm
+	switch {
+	case signer.DigestAlgorithm.Algorithm.Equal(oidDigestSHA256):
+		hash, algo = crypto.SHA256, x509.SHA256WithRSA
+	case signer.DigestAlgorithm.Algorithm.Equal(oidDigestSHA1):
+		hash, algo = crypto.SHA1, x509.SHA1WithRSA
+	default:
+		return fmt.Errorf("pkcs7: unsupported digest algorithm %s", signer.DigestAlgorithm.Algorithm)
+	}
+	encAlgo := signer.DigestEncryptionAlgorithm.Algorithm
+	if !encAlgo.Equal(oidEncryptionRSA) && !encAlgo.Equal(oidEncryptionRSASHA1) && !encAlgo.Equal(oidEncryptionRSASHA256) {
+		return fmt.Errorf("pkcs7: unsupported signature algorithm %s", encAlgo)
+	}
+	signed := content
+	if len(signer.AuthenticatedAttributes.Bytes) > 0 {
+		// 存在签名属性时，签名的是属性集合(DER SET)，内容摘要保存在 messageDigest 属性中
+		digest, err := pkcs7MessageDigest(signer.AuthenticatedAttributes.Bytes)
+		if err != nil {
+			return err
+		}
+		h := hash.New()
+		h.Write(content)
+		if !bytes.Equal(h.Sum(nil), digest) {
+			return errors.New("pkcs7: message digest mismatch")
+		}
+		signed = append([]byte{0x31}, signer.AuthenticatedAttributes.FullBytes[1:]...)
+	}
+	if err := cert.CheckSignature(algo, signed, signer.EncryptedDigest); err != nil {
+		return fmt.Errorf("pkcs7: %w", err)
+	}
+	return nil
+}
+
+func pkcs7MessageDigest(attrs []byte) ([]byte, error) {
+	for len(attrs) > 0 {
+		var attr pkcs7Attribute
+		rest, err := asn1.Unmarshal(attrs, &attr)
+		if err != nil {
+			return nil, fmt.Errorf("pkcs7: %w", err)
+		}
+		attrs = rest
+		if !attr.Type.Equal(oidAttrMessageDigest) {
+			continue
+		}
+		var digest []byte
+		if _, err = asn1.Unmarshal(attr.Values.Bytes, &digest); err != nil {
+			return nil, fmt.Errorf("pkcs7: %w", err)
+		}
+		return digest, nil
+	}
+	return nil, errors.New("pkcs7: messageDigest attribute not found")
+}
@@ -0,0 +1,30 @@
+package applepay
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestParseReceipt(t *testing.T) {
+	receiptByte, _ := os.ReadFile("test_verify_receipt.txt")
+	receipt, err := ParseReceipt(string(receiptByte))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, receipt.BundleId, "com.langaiapp.scanner")
+	assert.Equal(t, receipt.ReceiptType, "ProductionSandbox")
+	assert.NotEmpty(t, receipt.InApp)
+	inApp := receipt.InApp[0]
+	assert.Equal(t, inApp.ProductId, "com.langaiapp.scannerSubYear")
+	assert.Equal(t, inApp.OriginalTransactionId, "2000000447941430")
+	assert.Equal(t, inApp.PurchaseDateTimestamp, "1698750704000")
+	assert.Equal(t, inApp.IsTrialPeriod, "false")
+}
+
+func TestParseReceiptTampered(t *testing.T) {
+	receiptByte, _ := os.ReadFile("test_verify_receipt.txt")
+	der, _ := base64.StdEncoding.DecodeString(string(receiptByte))
+	der[200] ^= 0xff
+	_, err := ParseReceiptBytes(der)
+	assert.NotEqual(t, err, nil)
+}
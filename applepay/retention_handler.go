@@ -0,0 +1,123 @@
+package applepay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt"
+	"log"
+	"net/http"
+)
+
+// RetentionRealtimeRequest 实时消息请求中signedPayload解析后的内容
+// https://developer.apple.com/documentation/retentionmessaging/decodedrealtimerequestbody
+type RetentionRealtimeRequest struct {
+	jwt2.StandardClaims
+	OriginalTransactionId string `json:"originalTransactionId"`
+	AppAppleId            int64  `json:"appAppleId"`
+	ProductId             string `json:"productId"`
+	UserLocale            string `json:"userLocale"`
+	RequestIdentifier     string `json:"requestIdentifier"`
+	Environment           string `json:"environment"`
+	SignedDate            int64  `json:"signedDate"`
+}
+
+// RetentionRealtimeResponse 实时消息响应，message、alternateProduct、promotionalOffer 只能设置一个
+// https://developer.apple.com/documentation/retentionmessaging/realtimeresponsebody
+type RetentionRealtimeResponse struct {
+	Message          *RetentionRealtimeMessage          `json:"message,omitempty"`
+	AlternateProduct *RetentionRealtimeAlternateProduct `json:"alternateProduct,omitempty"`
+	PromotionalOffer *RetentionRealtimePromotionalOffer `json:"promotionalOffer,omitempty"`
+}
+
+type RetentionRealtimeMessage struct {
+	MessageIdentifier string `json:"messageIdentifier"`
+}
+
+type RetentionRealtimeAlternateProduct struct {
+	MessageIdentifier string `json:"messageIdentifier"`
+	ProductId         string `json:"productId"`
+}
+
+type RetentionRealtimePromotionalOffer struct {
+	MessageIdentifier string `json:"messageIdentifier"`
+	// PromotionalOfferSignatureV2 OfferSigner.SignPromotionalOffer 生成的 JWS
+	PromotionalOfferSignatureV2 string `json:"promotionalOfferSignatureV2,omitempty"`
+}
+
+// RetentionMessageFunc 根据实时请求选择要展示的消息，返回错误时响应500，苹果会展示默认消息
+type RetentionMessageFunc func(ctx context.Context, req *RetentionRealtimeRequest) (*RetentionRealtimeResponse, error)
+
+// RetentionMessageHandler Retention Messaging 实时消息接口的 http.Handler
+// 校验苹果签名后调用 RetentionMessageFunc，并以JSON返回消息ID
+type RetentionMessageHandler struct {
+	Func RetentionMessageFunc
+	// MaxBodyBytes 请求体大小限制，默认 DefaultNotificationMaxBodyBytes
+	MaxBodyBytes int64
+	// Verify 校验并解析signedPayload，默认 ExtractClaims
+	Verify func(signedPayload string, claims jwt2.Claims) error
+	// ErrorLog 为nil时使用log包的默认Logger
+	ErrorLog *log.Logger
+}
+
+func NewRetentionMessageHandler(fn RetentionMessageFunc) *RetentionMessageHandler {
+	return &RetentionMessageHandler{Func: fn}
+}
+
+func (h *RetentionMessageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	body, status, err := readNotificationBody(w, r, h.MaxBodyBytes)
+	if err != nil {
+		h.logf("applepay: read retention request: %v", err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	req, err := h.decode(body)
+	if err != nil {
+		h.logf("applepay: decode retention request: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	resp, err := h.Func(r.Context(), req)
+	if err == nil && resp == nil {
+		err = errors.New("retention response is nil")
+	}
+	if err != nil {
+		h.logf("applepay: handle retention request %s: %v", req.RequestIdentifier, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (h *RetentionMessageHandler) decode(body []byte) (*RetentionRealtimeRequest, error) {
+	payload := new(NotificationV2Request)
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, err
+	}
+	if payload.SignedPayload == "" {
+		return nil, errors.New("signedPayload is empty")
+	}
+	verify := h.Verify
+	if verify == nil {
+		verify = ExtractClaims
+	}
+	req := new(RetentionRealtimeRequest)
+	if err := verify(payload.SignedPayload, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (h *RetentionMessageHandler) logf(format string, args ...any) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,51 @@
+package applepay
+
+import (
+	"context"
+	"encoding/json"
+	jwt2 "github.com/golang-jwt/jwt"
+	"github.com/pkg6/applego/jwt"
+	"github.com/pkg6/applego/utility"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRetentionMessageHandler(t *testing.T) {
+	key, _ := utility.EcdsaPrivateKey([]byte(testOfferKey))
+	signedPayload, _ := jwt.Encode(key, jwt2.SigningMethodES256, &RetentionRealtimeRequest{
+		OriginalTransactionId: "1000000000000001",
+		ProductId:             "com.example.monthly",
+		UserLocale:            "en-US",
+		RequestIdentifier:     "req-1",
+		Environment:           "Sandbox",
+	}, map[string]any{"alg": "ES256"})
+	handler := NewRetentionMessageHandler(func(ctx context.Context, req *RetentionRealtimeRequest) (*RetentionRealtimeResponse, error) {
+		return &RetentionRealtimeResponse{Message: &RetentionRealtimeMessage{MessageIdentifier: "stay-" + req.UserLocale}}, nil
+	})
+	handler.ErrorLog = log.New(io.Discard, "", 0)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	body, _ := json.Marshal(NotificationV2Request{SignedPayload: signedPayload})
+
+	// 没有苹果证书链，默认校验失败
+	resp, _ := http.Post(server.URL, "application/json", strings.NewReader(string(body)))
+	assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+
+	handler.Verify = func(signedPayload string, claims jwt2.Claims) error {
+		_, err := jwt2.ParseWithClaims(signedPayload, claims, func(token *jwt2.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		})
+		return err
+	}
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(string(body)))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	result := new(RetentionRealtimeResponse)
+	_ = json.NewDecoder(resp.Body).Decode(result)
+	assert.Equal(t, result.Message.MessageIdentifier, "stay-en-US")
+}
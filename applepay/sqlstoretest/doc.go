@@ -0,0 +1,3 @@
+// Package sqlstoretest 使用sqlite测试 applepay.SQLNotificationStore
+// 独立的module，避免 applego 依赖cgo的sqlite驱动：cd applepay/sqlstoretest && go test ./...
+package sqlstoretest
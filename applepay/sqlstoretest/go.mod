@@ -0,0 +1,22 @@
+module github.com/pkg6/applego/applepay/sqlstoretest
+
+go 1.18
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.17
+	github.com/pkg6/applego v0.0.0
+	github.com/stretchr/testify v1.8.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/golang-jwt/jwt v3.2.2+incompatible // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/pkg6/go-requests v0.2.3 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/crypto v0.14.0 // indirect
+	golang.org/x/net v0.17.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/pkg6/applego => ../..
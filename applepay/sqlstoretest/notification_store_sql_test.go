@@ -0,0 +1,107 @@
+//go:build cgo
+
+package sqlstoretest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg6/applego/applepay"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestSQLNotificationStore(t *testing.T) *applepay.SQLNotificationStore {
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "notifications.db")+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	store := applepay.NewSQLNotificationStore(db)
+	if err = store.CreateTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestSQLNotificationStore(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLNotificationStore(t)
+	record := &applepay.NotificationRecord{NotificationUUID: "uuid-1", NotificationType: "DID_RENEW", SignedPayload: "payload"}
+
+	ok, err := store.Begin(ctx, record)
+	assert.Equal(t, err, nil)
+	assert.True(t, ok)
+	// 处理中
+	ok, _ = store.Begin(ctx, record)
+	assert.False(t, ok)
+
+	assert.Equal(t, store.Finish(ctx, "uuid-1", errors.New("db down")), nil)
+	got, _ := store.Get(ctx, "uuid-1")
+	assert.Equal(t, got.Status, applepay.NotificationStatusFailed)
+	assert.Equal(t, got.LastError, "db down")
+	ok, _ = store.Begin(ctx, record)
+	assert.True(t, ok)
+
+	assert.Equal(t, store.Finish(ctx, "uuid-1", nil), nil)
+	ok, _ = store.Begin(ctx, record)
+	assert.False(t, ok)
+	got, _ = store.Get(ctx, "uuid-1")
+	assert.Equal(t, got.Status, applepay.NotificationStatusSucceeded)
+	assert.Equal(t, got.Attempts, 2)
+	assert.Equal(t, got.SignedPayload, "payload")
+
+	// 处理超时的记录可以重新处理
+	_, _ = store.Begin(ctx, &applepay.NotificationRecord{NotificationUUID: "uuid-2"})
+	_, err = store.DB.Exec(`UPDATE apple_notifications SET updated_at = ? WHERE notification_uuid = ?`, time.Now().Add(-time.Hour).UnixMilli(), "uuid-2")
+	assert.Equal(t, err, nil)
+	ok, _ = store.Begin(ctx, &applepay.NotificationRecord{NotificationUUID: "uuid-2"})
+	assert.True(t, ok)
+
+	records, _ := store.List(ctx, applepay.NotificationStatusProcessing)
+	assert.Equal(t, len(records), 1)
+	assert.Equal(t, records[0].NotificationUUID, "uuid-2")
+	got, _ = store.Get(ctx, "missing")
+	assert.Nil(t, got)
+}
+
+func TestSQLNotificationStoreConcurrentBegin(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLNotificationStore(t)
+	begin := func(uuid string) int32 {
+		var started int32
+		var wg sync.WaitGroup
+		ready := make(chan struct{})
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-ready
+				ok, err := store.Begin(ctx, &applepay.NotificationRecord{NotificationUUID: uuid, NotificationType: "DID_RENEW"})
+				assert.Equal(t, err, nil)
+				if ok {
+					atomic.AddInt32(&started, 1)
+				}
+			}()
+		}
+		close(ready)
+		wg.Wait()
+		return started
+	}
+	for i := 0; i < 20; i++ {
+		uuid := fmt.Sprintf("uuid-%d", i)
+		// 首次并发投递
+		assert.Equal(t, begin(uuid), int32(1))
+		// 处理失败后并发重试
+		assert.Equal(t, store.Finish(ctx, uuid, errors.New("failed")), nil)
+		assert.Equal(t, begin(uuid), int32(1))
+		got, _ := store.Get(ctx, uuid)
+		assert.Equal(t, got.Attempts, 2)
+	}
+}
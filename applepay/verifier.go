@@ -0,0 +1,173 @@
+package applepay
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	jwt2 "github.com/golang-jwt/jwt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var defaultSignedDataVerifier = &SignedDataVerifier{}
+
+// SignedDataVerifier 校验JWS的x5c证书链并解析数据
+// 默认信任苹果根证书 Apple Root CA - G3，测试时可以信任 applepaytest 生成的根证书
+type SignedDataVerifier struct {
+	// RootPEM 受信任的根证书，为空时使用苹果根证书
+	RootPEM []byte
+}
+
+// NewSignedDataVerifier 创建信任指定根证书的校验器
+func NewSignedDataVerifier(rootPEM []byte) *SignedDataVerifier {
+	return &SignedDataVerifier{RootPEM: rootPEM}
+}
+
+func (v *SignedDataVerifier) rootPEM() []byte {
+	if len(v.RootPEM) == 0 {
+		return []byte(rootPEM)
+	}
+	return v.RootPEM
+}
+
+// ExtractClaims 校验证书链后使用叶子证书的公钥解析jws数据，claims 必须为指针
+// 叶子证书 x5c[0] 需由中间证书 x5c[1] 签发且链到受信任的根证书，只接受ES256签名
+// 证书有效期以数据中的 signedDate 为准，与苹果官方库一致，没有 signedDate 时使用当前时间
+func (v *SignedDataVerifier) ExtractClaims(signedPayload string, claims jwt2.Claims) error {
+	if reflect.ValueOf(claims).Kind() != reflect.Ptr {
+		return errors.New("tran must be ptr struct")
+	}
+	leafKey, err := v.verifyChain(signedPayload)
+	if err != nil {
+		return err
+	}
+	parser := &jwt2.Parser{ValidMethods: []string{jwt2.SigningMethodES256.Alg()}}
+	_, err = parser.ParseWithClaims(signedPayload, claims, func(token *jwt2.Token) (any, error) {
+		return leafKey, nil
+	})
+	return err
+}
+
+// verifyChain 校验x5c证书链，返回叶子证书的公钥
+// Per doc: https://datatracker.ietf.org/doc/html/rfc7515#section-4.1.6
+func (v *SignedDataVerifier) verifyChain(signedPayload string) (*ecdsa.PublicKey, error) {
+	var certs []*x509.Certificate
+	for i := 0; i < 3; i++ {
+		der, err := extractHeaderByIndex(signedPayload, i)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("x5c[%d]: %w", i, err)
+		}
+		certs = append(certs, cert)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(v.rootPEM()) {
+		return nil, errors.New("failed to parse root certificate")
+	}
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(certs[1])
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   signedTime(signedPayload),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, err
+	}
+	leafKey, ok := certs[0].PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("appstore public key must be of type ecdsa.PublicKey")
+	}
+	return leafKey, nil
+}
+
+// signedTime 读取未校验的 signedDate，只用于确定证书的校验时间
+func signedTime(signedPayload string) time.Time {
+	parts := strings.Split(signedPayload, ".")
+	if len(parts) != 3 {
+		return time.Now()
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Now()
+	}
+	var payload struct {
+		SignedDate int64 `json:"signedDate"`
+	}
+	if err = json.Unmarshal(b, &payload); err != nil || payload.SignedDate <= 0 {
+		return time.Now()
+	}
+	return time.UnixMilli(payload.SignedDate)
+}
+
+// DecodeTransactionInfo 解析 signedTransactionInfo
+func (v *SignedDataVerifier) DecodeTransactionInfo(signedTransactionInfo string) (*TransactionInfo, error) {
+	if signedTransactionInfo == "" {
+		return nil, errors.New("signedTransactionInfo is empty")
+	}
+	ti := new(TransactionInfo)
+	if err := v.ExtractClaims(signedTransactionInfo, ti); err != nil {
+		return nil, err
+	}
+	return ti, nil
+}
+
+// DecodeRenewalInfo 解析 signedRenewalInfo
+func (v *SignedDataVerifier) DecodeRenewalInfo(signedRenewalInfo string) (*RenewalInfo, error) {
+	if signedRenewalInfo == "" {
+		return nil, errors.New("signedRenewalInfo is empty")
+	}
+	ri := new(RenewalInfo)
+	if err := v.ExtractClaims(signedRenewalInfo, ri); err != nil {
+		return nil, err
+	}
+	return ri, nil
+}
+
+// DecodeSignedPayload 解析通知的 signedPayload
+func (v *SignedDataVerifier) DecodeSignedPayload(signedPayload string) (*NotificationV2Payload, error) {
+	if signedPayload == "" {
+		return nil, errors.New("signedPayload is empty")
+	}
+	payload := new(NotificationV2Payload)
+	if err := v.ExtractClaims(signedPayload, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// DecodeNotificationV2 校验并解析通知以及其中的续订、交易信息，opts 与 DecodeNotificationV2 相同
+func (v *SignedDataVerifier) DecodeNotificationV2(signedPayload string, opts *NotificationV2DecodeOptions) (*NotificationV2SignedPayloadResponse, error) {
+	payload, err := v.DecodeSignedPayload(signedPayload)
+	if err != nil {
+		return nil, err
+	}
+	return v.decodeNotificationV2Payload(payload, opts)
+}
+
+func (v *SignedDataVerifier) decodeNotificationV2Payload(payload *NotificationV2Payload, opts *NotificationV2DecodeOptions) (resp *NotificationV2SignedPayloadResponse, err error) {
+	resp = &NotificationV2SignedPayloadResponse{Payload: payload}
+	allowMissing := opts != nil && opts.AllowMissing
+	data := payload.Data
+	if !allowMissing && data == nil {
+		return nil, errors.New("data is nil")
+	}
+	if !allowMissing || (data != nil && data.SignedRenewalInfo != "") {
+		if resp.RenewalInfo, err = v.DecodeRenewalInfo(data.SignedRenewalInfo); err != nil {
+			return nil, err
+		}
+	}
+	if !allowMissing || (data != nil && data.SignedTransactionInfo != "") {
+		if resp.TransactionInfo, err = v.DecodeTransactionInfo(data.SignedTransactionInfo); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,101 @@
+package applepay
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	jwt2 "github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestExtractHeaderByIndex(t *testing.T) {
+	cert := base64.StdEncoding.EncodeToString([]byte{0xfb, 0xff})
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","x5c":["` + cert + `","` + cert + `"],"kid":"???"}`))
+	certByte, err := extractHeaderByIndex(header+".e30.sig", 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, certByte, []byte{0xfb, 0xff})
+	_, err = extractHeaderByIndex(header+".e30.sig", 2)
+	assert.NotEqual(t, err, nil)
+	err = NewSignedDataVerifier(nil).ExtractClaims(header+".e30.sig", &TransactionInfo{})
+	assert.NotEqual(t, err, nil)
+}
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, curve elliptic.Curve, cn string, isCA bool, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key}
+}
+
+func signTestJWS(t *testing.T, method jwt2.SigningMethod, leaf *testCert, chain ...*testCert) string {
+	x5c := []string{base64.StdEncoding.EncodeToString(leaf.cert.Raw)}
+	for _, c := range chain {
+		x5c = append(x5c, base64.StdEncoding.EncodeToString(c.cert.Raw))
+	}
+	token := jwt2.NewWithClaims(method, &TransactionInfo{TransactionId: "1000", SignedDate: time.Now().UnixMilli()})
+	token.Header["x5c"] = x5c
+	signed, err := token.SignedString(leaf.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestSignedDataVerifierChain(t *testing.T) {
+	root := newTestCert(t, elliptic.P256(), "root", true, nil)
+	intermediate := newTestCert(t, elliptic.P256(), "intermediate", true, root)
+	leaf := newTestCert(t, elliptic.P256(), "leaf", false, intermediate)
+	verifier := NewSignedDataVerifier(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.cert.Raw}))
+
+	ti, err := verifier.DecodeTransactionInfo(signTestJWS(t, jwt2.SigningMethodES256, leaf, intermediate, root))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ti.TransactionId, "1000")
+
+	// 自签名的叶子证书附带真实的中间证书与根证书
+	forged := newTestCert(t, elliptic.P256(), "leaf", false, nil)
+	_, err = verifier.DecodeTransactionInfo(signTestJWS(t, jwt2.SigningMethodES256, forged, intermediate, root))
+	assert.NotEqual(t, err, nil)
+
+	// 其他根证书签发的证书链
+	otherRoot := newTestCert(t, elliptic.P256(), "root", true, nil)
+	otherIntermediate := newTestCert(t, elliptic.P256(), "intermediate", true, otherRoot)
+	otherLeaf := newTestCert(t, elliptic.P256(), "leaf", false, otherIntermediate)
+	_, err = verifier.DecodeTransactionInfo(signTestJWS(t, jwt2.SigningMethodES256, otherLeaf, otherIntermediate, root))
+	assert.NotEqual(t, err, nil)
+
+	// 只接受ES256
+	p384Leaf := newTestCert(t, elliptic.P384(), "leaf", false, intermediate)
+	_, err = verifier.DecodeTransactionInfo(signTestJWS(t, jwt2.SigningMethodES384, p384Leaf, intermediate, root))
+	assert.NotEqual(t, err, nil)
+}
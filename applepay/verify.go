@@ -2,7 +2,9 @@ package applepay
 
 import (
 	"context"
+	"errors"
 	"github.com/pkg6/go-requests"
+	"time"
 )
 
 const (
@@ -18,7 +20,91 @@ const (
 // 文档：https://developer.apple.com/documentation/appstorereceipts/verifyreceipt
 func VerifyReceipt(ctx context.Context, url, pwd, receipt string) (resp *VerifyResponse, err error) {
 	req := &VerifyRequest{Receipt: receipt, Password: pwd}
+	return verifyReceipt(ctx, requests.New(), url, req)
+}
+
+func verifyReceipt(ctx context.Context, client *requests.Client, url string, req *VerifyRequest) (resp *VerifyResponse, err error) {
 	resp = new(VerifyResponse)
-	err = requests.New().PostJsonUnmarshal(ctx, url, req, &resp)
+	err = client.PostJsonUnmarshal(ctx, url, req, &resp)
 	return resp, err
 }
+
+// ReceiptVerifier 自动选择环境的票据校验
+// 先请求正式环境，返回21007时改为请求沙盒环境，返回21008时反之
+// status 不为0时返回 *VerifyStatusError，可使用 errors.Is(err, ErrReceiptSharedSecretMismatch) 等判断
+type ReceiptVerifier struct {
+	// Password 苹果APP秘钥
+	Password string
+	// ExcludeOldTransactions 只返回订阅的最新续订记录
+	ExcludeOldTransactions bool
+	// SandboxFirst 先请求沙盒环境，适用于测试服务
+	SandboxFirst bool
+	// MaxRetries 临时错误(is-retryable等)的最大重试次数
+	MaxRetries int
+	// RetryWait 重试间隔
+	RetryWait time.Duration
+	// ProductionURL 默认 UrlProd
+	ProductionURL string
+	// SandboxURL 默认 UrlSandbox
+	SandboxURL string
+	//Request client
+	Client *requests.Client
+}
+
+func NewReceiptVerifier(pwd string) *ReceiptVerifier {
+	return &ReceiptVerifier{
+		Password:      pwd,
+		MaxRetries:    2,
+		RetryWait:     time.Second,
+		ProductionURL: UrlProd,
+		SandboxURL:    UrlSandbox,
+	}
+}
+
+// Verify 校验票据，status 不为0时 resp 与 err 同时返回
+func (v *ReceiptVerifier) Verify(ctx context.Context, receipt string) (resp *VerifyResponse, err error) {
+	prodURL, sandboxURL := v.ProductionURL, v.SandboxURL
+	if prodURL == "" {
+		prodURL = UrlProd
+	}
+	if sandboxURL == "" {
+		sandboxURL = UrlSandbox
+	}
+	url, otherURL := prodURL, sandboxURL
+	if v.SandboxFirst {
+		url, otherURL = sandboxURL, prodURL
+	}
+	resp, err = v.verify(ctx, url, receipt)
+	if errors.Is(err, ErrReceiptSandbox) || errors.Is(err, ErrReceiptProduction) {
+		resp, err = v.verify(ctx, otherURL, receipt)
+	}
+	return resp, err
+}
+
+func (v *ReceiptVerifier) verify(ctx context.Context, url, receipt string) (resp *VerifyResponse, err error) {
+	client := v.Client
+	if client == nil {
+		client = requests.New()
+	}
+	req := &VerifyRequest{
+		Receipt:                receipt,
+		Password:               v.Password,
+		ExcludeOldTranscations: v.ExcludeOldTransactions,
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err = verifyReceipt(ctx, client, url, req)
+		if err != nil {
+			return nil, err
+		}
+		err = VerifyStatusErr(resp)
+		var statusErr *VerifyStatusError
+		if !errors.As(err, &statusErr) || !statusErr.Temporary() || attempt >= v.MaxRetries {
+			return resp, err
+		}
+		select {
+		case <-ctx.Done():
+			return resp, ctx.Err()
+		case <-time.After(v.RetryWait):
+		}
+	}
+}
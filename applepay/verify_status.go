@@ -0,0 +1,104 @@
+package applepay
+
+import (
+	"fmt"
+)
+
+// verifyReceipt 响应状态码
+// https://developer.apple.com/documentation/appstorereceipts/status
+const (
+	VerifyStatusOK                   = 0
+	VerifyStatusBadMethod            = 21000
+	VerifyStatusDeprecated           = 21001
+	VerifyStatusMalformedReceipt     = 21002
+	VerifyStatusNotAuthenticated     = 21003
+	VerifyStatusSharedSecretMismatch = 21004
+	VerifyStatusServerUnavailable    = 21005
+	VerifyStatusSubscriptionExpired  = 21006
+	VerifyStatusSandboxReceipt       = 21007
+	VerifyStatusProductionReceipt    = 21008
+	VerifyStatusInternalDataAccess   = 21009
+	VerifyStatusAccountNotFound      = 21010
+)
+
+var (
+	ErrReceiptBadMethod            = &VerifyStatusError{Status: VerifyStatusBadMethod}
+	ErrReceiptDeprecated           = &VerifyStatusError{Status: VerifyStatusDeprecated}
+	ErrReceiptMalformed            = &VerifyStatusError{Status: VerifyStatusMalformedReceipt}
+	ErrReceiptNotAuthenticated     = &VerifyStatusError{Status: VerifyStatusNotAuthenticated}
+	ErrReceiptSharedSecretMismatch = &VerifyStatusError{Status: VerifyStatusSharedSecretMismatch}
+	ErrReceiptServerUnavailable    = &VerifyStatusError{Status: VerifyStatusServerUnavailable}
+	ErrReceiptSubscriptionExpired  = &VerifyStatusError{Status: VerifyStatusSubscriptionExpired}
+	ErrReceiptSandbox              = &VerifyStatusError{Status: VerifyStatusSandboxReceipt}
+	ErrReceiptProduction           = &VerifyStatusError{Status: VerifyStatusProductionReceipt}
+	ErrReceiptInternalDataAccess   = &VerifyStatusError{Status: VerifyStatusInternalDataAccess}
+	ErrReceiptAccountNotFound      = &VerifyStatusError{Status: VerifyStatusAccountNotFound}
+	// ErrReceiptInternal 匹配 21100-21199 范围内的所有状态码
+	ErrReceiptInternal = &VerifyStatusError{Status: 21100}
+)
+
+var verifyStatusMessages = map[int]string{
+	VerifyStatusBadMethod:            "the request to the App Store was not made using the HTTP POST request method",
+	VerifyStatusDeprecated:           "this status code is no longer sent by the App Store",
+	VerifyStatusMalformedReceipt:     "the data in the receipt-data property was malformed or the service experienced a temporary issue",
+	VerifyStatusNotAuthenticated:     "the receipt could not be authenticated",
+	VerifyStatusSharedSecretMismatch: "the shared secret you provided does not match the shared secret on file for your account",
+	VerifyStatusServerUnavailable:    "the receipt server was temporarily unable to provide the receipt",
+	VerifyStatusSubscriptionExpired:  "this receipt is valid but the subscription has expired",
+	VerifyStatusSandboxReceipt:       "this receipt is from the test environment, but it was sent to the production environment for verification",
+	VerifyStatusProductionReceipt:    "this receipt is from the production environment, but it was sent to the test environment for verification",
+	VerifyStatusInternalDataAccess:   "internal data access error",
+	VerifyStatusAccountNotFound:      "the user account cannot be found or has been deleted",
+}
+
+// VerifyStatusError verifyReceipt 返回的 status 不为0时的错误
+// 可以使用 errors.Is(err, ErrReceiptSandbox) 判断具体的状态码
+type VerifyStatusError struct {
+	Status int
+	// IsRetryable 对应响应中的 is-retryable，仅 21100-21199 有效
+	IsRetryable bool
+	Environment string
+}
+
+func (e *VerifyStatusError) Error() string {
+	msg, ok := verifyStatusMessages[e.Status]
+	if !ok && e.isInternal() {
+		msg = "internal data access error"
+	} else if !ok {
+		msg = "unknown status"
+	}
+	return fmt.Sprintf("verify receipt status %d: %s", e.Status, msg)
+}
+
+func (e *VerifyStatusError) Is(target error) bool {
+	t, ok := target.(*VerifyStatusError)
+	if !ok {
+		return false
+	}
+	if t.isInternal() {
+		return e.isInternal()
+	}
+	return t.Status == e.Status
+}
+
+// Temporary 是否为临时错误，稍后重试可能成功
+// 21100-21199 以响应中的 is-retryable 为准
+func (e *VerifyStatusError) Temporary() bool {
+	switch e.Status {
+	case VerifyStatusMalformedReceipt, VerifyStatusServerUnavailable, VerifyStatusInternalDataAccess:
+		return true
+	}
+	return e.isInternal() && e.IsRetryable
+}
+
+func (e *VerifyStatusError) isInternal() bool {
+	return e.Status >= 21100 && e.Status <= 21199
+}
+
+// VerifyStatusErr 将响应的 status 转换为错误，status 为0时返回nil
+func VerifyStatusErr(resp *VerifyResponse) error {
+	if resp == nil || resp.Status == VerifyStatusOK {
+		return nil
+	}
+	return &VerifyStatusError{Status: resp.Status, IsRetryable: resp.IsRetryable, Environment: resp.Environment}
+}
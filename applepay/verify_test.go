@@ -2,7 +2,11 @@ package applepay
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 )
@@ -14,3 +18,45 @@ func TestVerifyReceipt(t *testing.T) {
 	assert.Equal(t, err, nil)
 	assert.Equal(t, resp.Environment, "Sandbox")
 }
+
+func TestReceiptVerifier(t *testing.T) {
+	var calls []string
+	var retried bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		var req VerifyRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		resp := VerifyResponse{Status: VerifyStatusOK, Environment: "Sandbox"}
+		switch {
+		case req.Password != "secret":
+			resp.Status = VerifyStatusSharedSecretMismatch
+		case r.URL.Path == "/prod":
+			resp.Status = VerifyStatusSandboxReceipt
+		case req.Receipt == "retry" && !retried:
+			retried = true
+			resp.Status, resp.IsRetryable = 21199, true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+	verifier := NewReceiptVerifier("secret")
+	verifier.ProductionURL = server.URL + "/prod"
+	verifier.SandboxURL = server.URL + "/sandbox"
+	verifier.RetryWait = 0
+
+	resp, err := verifier.Verify(context.Background(), "receipt")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, resp.Environment, "Sandbox")
+	assert.Equal(t, calls, []string{"/prod", "/sandbox"})
+
+	calls = nil
+	_, err = verifier.Verify(context.Background(), "retry")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, calls, []string{"/prod", "/sandbox", "/sandbox"})
+
+	verifier.Password = "wrong"
+	resp, err = verifier.Verify(context.Background(), "receipt")
+	assert.True(t, errors.Is(err, ErrReceiptSharedSecretMismatch))
+	assert.Equal(t, resp.Status, VerifyStatusSharedSecretMismatch)
+}
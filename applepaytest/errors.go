@@ -0,0 +1,50 @@
+package applepaytest
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// App Store Server API 错误码
+// https://developer.apple.com/documentation/appstoreserverapi/error_codes
+const (
+	ErrorCodeGeneralBadRequest             = 4000000
+	ErrorCodeInvalidRequestRevision        = 4000005
+	ErrorCodeInvalidTransactionId          = 4000006
+	ErrorCodeInvalidStatus                 = 4000031
+	ErrorCodeInvalidPaginationToken        = 4000103
+	ErrorCodeInvalidStartDate              = 4000104
+	ErrorCodeInvalidEndDate                = 4000105
+	ErrorCodeOriginalTransactionIdNotFound = 4040005
+	ErrorCodeTransactionIdNotFound         = 4040010
+	ErrorCodeRateLimitExceeded             = 4290000
+	ErrorCodeGeneralInternal               = 5000000
+)
+
+var errorMessages = map[int]string{
+	ErrorCodeGeneralBadRequest:             "Bad request.",
+	ErrorCodeInvalidRequestRevision:        "Invalid request revision.",
+	ErrorCodeInvalidTransactionId:          "Invalid transaction id.",
+	ErrorCodeInvalidStatus:                 "Invalid status.",
+	ErrorCodeInvalidPaginationToken:        "Invalid pagination token.",
+	ErrorCodeInvalidStartDate:              "Invalid start date.",
+	ErrorCodeInvalidEndDate:                "Invalid end date.",
+	ErrorCodeOriginalTransactionIdNotFound: "Original transaction id not found.",
+	ErrorCodeTransactionIdNotFound:         "Transaction id not found.",
+	ErrorCodeRateLimitExceeded:             "Rate limit exceeded.",
+	ErrorCodeGeneralInternal:               "An unknown error occurred.",
+}
+
+// writeError 按苹果的格式返回错误，HTTP状态码为错误码的前三位
+func writeError(w http.ResponseWriter, errorCode int) {
+	writeJSON(w, errorCode/10000, map[string]any{
+		"errorCode":    errorCode,
+		"errorMessage": errorMessages[errorCode],
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,69 @@
+package applepaytest
+
+import (
+	"github.com/pkg6/applego/applepay"
+	"github.com/pkg6/applego/utility"
+	"time"
+)
+
+// Verifier 信任该PKI根证书的校验器，用于解析 Sign* 生成的数据
+func (p *PKI) Verifier() *applepay.SignedDataVerifier {
+	return applepay.NewSignedDataVerifier(p.RootPEM())
+}
+
+// SignTransaction 生成 signedTransactionInfo，signedDate 为空时使用当前时间
+func (p *PKI) SignTransaction(ti *applepay.TransactionInfo) (string, error) {
+	claims := *ti
+	if claims.SignedDate == 0 {
+		claims.SignedDate = time.Now().UnixMilli()
+	}
+	return p.Sign(&claims)
+}
+
+// SignRenewal 生成 signedRenewalInfo，signedDate 为空时使用当前时间
+func (p *PKI) SignRenewal(ri *applepay.RenewalInfo) (string, error) {
+	claims := *ri
+	if claims.SignedDate == 0 {
+		claims.SignedDate = time.Now().UnixMilli()
+	}
+	return p.Sign(&claims)
+}
+
+// SignNotification 生成通知的 signedPayload，未设置的 notificationUUID、version、signedDate 自动生成
+func (p *PKI) SignNotification(payload *applepay.NotificationV2Payload) (string, error) {
+	claims := *payload
+	if claims.NotificationUUID == "" {
+		uuid, err := utility.NewUUID()
+		if err != nil {
+			return "", err
+		}
+		claims.NotificationUUID = uuid
+	}
+	if claims.Version == "" {
+		claims.Version = "2.0"
+	}
+	if claims.SignedDate == 0 {
+		claims.SignedDate = time.Now().UnixMilli()
+	}
+	return p.Sign(&claims)
+}
+
+// NotificationData 签名交易与续订信息并生成通知的 data，ti、ri 可以为nil
+func (p *PKI) NotificationData(ti *applepay.TransactionInfo, ri *applepay.RenewalInfo) (data *applepay.Data, err error) {
+	data = &applepay.Data{Environment: DefaultEnvironment}
+	if ti != nil {
+		data.BundleID = ti.BundleId
+		if ti.Environment != "" {
+			data.Environment = ti.Environment
+		}
+		if data.SignedTransactionInfo, err = p.SignTransaction(ti); err != nil {
+			return nil, err
+		}
+	}
+	if ri != nil {
+		if data.SignedRenewalInfo, err = p.SignRenewal(ri); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
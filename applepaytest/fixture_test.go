@@ -0,0 +1,47 @@
+package applepaytest
+
+import (
+	"github.com/pkg6/applego/applepay"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSignNotification(t *testing.T) {
+	pki, err := NewPKI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := pki.NotificationData(
+		&applepay.TransactionInfo{TransactionId: "1000", OriginalTransactionId: "1000", BundleId: "com.example.app"},
+		&applepay.RenewalInfo{OriginalTransactionId: "1000", AutoRenewStatus: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedPayload, err := pki.SignNotification(&applepay.NotificationV2Payload{
+		NotificationType: "DID_RENEW",
+		Data:             data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := pki.Verifier().DecodeNotificationV2(signedPayload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, resp.Payload.NotificationType, "DID_RENEW")
+	assert.Equal(t, resp.Payload.Version, "2.0")
+	assert.Equal(t, resp.Payload.Data.BundleID, "com.example.app")
+	assert.Equal(t, resp.TransactionInfo.TransactionId, "1000")
+	assert.Equal(t, resp.RenewalInfo.AutoRenewStatus, int64(1))
+
+	// 默认只信任苹果根证书
+	_, err = applepay.DecodeNotificationV2(signedPayload, nil)
+	assert.NotEqual(t, err, nil)
+	other, err := NewPKI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = other.Verifier().DecodeSignedPayload(signedPayload)
+	assert.NotEqual(t, err, nil)
+}
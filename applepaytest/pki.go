@@ -0,0 +1,199 @@
+package applepaytest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	jwt2 "github.com/golang-jwt/jwt"
+	"github.com/pkg6/applego/jwt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	// 苹果在中间证书与叶子证书中使用的扩展
+	oidAppleIntermediate = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 2, 1}
+	oidAppleLeaf         = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 11, 1}
+)
+
+// PKI 模拟苹果的证书链：根证书、中间证书与叶子证书，用于生成带x5c的JWS
+type PKI struct {
+	Root         *x509.Certificate
+	Intermediate *x509.Certificate
+	Leaf         *x509.Certificate
+	// LeafKey 签名JWS的私钥
+	LeafKey *ecdsa.PrivateKey
+}
+
+// NewPKI 生成有效期10年的测试证书链
+func NewPKI() (*PKI, error) {
+	now := time.Now().Add(-time.Hour)
+	notAfter := now.AddDate(10, 0, 0)
+	rootKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	root, err := createCertificate(&x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               appleName("Apple Root CA - G3 (Test)"),
+		NotBefore:             now,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		return nil, err
+	}
+	intermediateKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	intermediate, err := createCertificate(&x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               appleName("Apple Worldwide Developer Relations Certification Authority (Test)"),
+		NotBefore:             now,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+		ExtraExtensions:       []pkix.Extension{{Id: oidAppleIntermediate, Value: []byte{0x05, 0x00}}},
+	}, root, &intermediateKey.PublicKey, rootKey)
+	if err != nil {
+		return nil, err
+	}
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := createCertificate(&x509.Certificate{
+		SerialNumber:    big.NewInt(3),
+		Subject:         appleName("Prod ECC Mac App Store and iTunes Store Receipt Signing (Test)"),
+		NotBefore:       now,
+		NotAfter:        notAfter,
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{{Id: oidAppleLeaf, Value: []byte{0x05, 0x00}}},
+	}, intermediate, &leafKey.PublicKey, intermediateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &PKI{Root: root, Intermediate: intermediate, Leaf: leaf, LeafKey: leafKey}, nil
+}
+
+func appleName(commonName string) pkix.Name {
+	return pkix.Name{
+		CommonName:         commonName,
+		OrganizationalUnit: []string{"Apple Certification Authority"},
+		Organization:       []string{"Apple Inc."},
+		Country:            []string{"US"},
+	}
+}
+
+func createCertificate(template, parent *x509.Certificate, pub *ecdsa.PublicKey, signer *ecdsa.PrivateKey) (*x509.Certificate, error) {
+	if parent == nil {
+		parent = template
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+// RootPEM 根证书的PEM编码
+func (p *PKI) RootPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: p.Root.Raw})
+}
+
+// PKI 保存到目录时使用的文件名
+const (
+	RootCertFile         = "root.pem"
+	IntermediateCertFile = "intermediate.pem"
+	LeafCertFile         = "leaf.pem"
+	LeafKeyFile          = "leaf-key.pem"
+)
+
+// WriteFiles 保存证书链与叶子证书私钥，webhook 可以读取 root.pem 校验签名
+func (p *PKI) WriteFiles(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(p.LeafKey)
+	if err != nil {
+		return err
+	}
+	files := map[string]*pem.Block{
+		RootCertFile:         {Type: "CERTIFICATE", Bytes: p.Root.Raw},
+		IntermediateCertFile: {Type: "CERTIFICATE", Bytes: p.Intermediate.Raw},
+		LeafCertFile:         {Type: "CERTIFICATE", Bytes: p.Leaf.Raw},
+		LeafKeyFile:          {Type: "EC PRIVATE KEY", Bytes: keyDer},
+	}
+	for name, block := range files {
+		if err = os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadPKI 读取 WriteFiles 保存的证书链
+func LoadPKI(dir string) (*PKI, error) {
+	blocks := make(map[string][]byte, 4)
+	for _, name := range []string{RootCertFile, IntermediateCertFile, LeafCertFile, LeafKeyFile} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(b)
+		if block == nil {
+			return nil, fmt.Errorf("%s: invalid pem", name)
+		}
+		blocks[name] = block.Bytes
+	}
+	p := &PKI{}
+	var err error
+	if p.Root, err = x509.ParseCertificate(blocks[RootCertFile]); err != nil {
+		return nil, err
+	}
+	if p.Intermediate, err = x509.ParseCertificate(blocks[IntermediateCertFile]); err != nil {
+		return nil, err
+	}
+	if p.Leaf, err = x509.ParseCertificate(blocks[LeafCertFile]); err != nil {
+		return nil, err
+	}
+	if p.LeafKey, err = x509.ParseECPrivateKey(blocks[LeafKeyFile]); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// X5c JWS header 中的证书链，顺序为叶子证书、中间证书、根证书
+func (p *PKI) X5c() []string {
+	return []string{
+		base64.StdEncoding.EncodeToString(p.Leaf.Raw),
+		base64.StdEncoding.EncodeToString(p.Intermediate.Raw),
+		base64.StdEncoding.EncodeToString(p.Root.Raw),
+	}
+}
+
+// Sign 使用叶子证书私钥签名，生成与苹果格式一致的JWS
+func (p *PKI) Sign(claims jwt2.Claims) (string, error) {
+	return jwt.Encode(p.LeafKey, jwt2.SigningMethodES256, claims, map[string]any{
+		"alg": "ES256",
+		"x5c": p.X5c(),
+	})
+}
+
+// Verify 使用该PKI的根证书校验JWS的证书链与签名，并解析到claims
+func (p *PKI) Verify(signedPayload string, claims jwt2.Claims) error {
+	return p.Verifier().ExtractClaims(signedPayload, claims)
+}
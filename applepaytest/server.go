@@ -0,0 +1,700 @@
+package applepaytest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt"
+	"github.com/pkg6/applego/applepay"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPageSize 每页返回的交易或通知数量
+	DefaultPageSize = 20
+	// DefaultEnvironment 返回数据中的environment
+	DefaultEnvironment = "Sandbox"
+
+	audience = "appstoreconnect-v1"
+)
+
+// Server 本地模拟的 App Store Server API，数据保存在内存中，响应使用 PKI 签名
+// ApiClient 通过 NewApiClient 或 Client.SetBaseURL(server.URL) 访问
+type Server struct {
+	*httptest.Server
+	PKI *PKI
+	// Issuer BundleID KeyID 用于校验请求中的 bearer token
+	Issuer   string
+	BundleID string
+	KeyID    string
+	// PublicKey 不为空时校验 bearer token 的签名
+	PublicKey   *ecdsa.PublicKey
+	AppAppleID  int
+	Environment string
+	PageSize    int
+
+	mu            sync.RWMutex
+	transactions  map[string]*applepay.TransactionInfo
+	renewals      map[string]*applepay.RenewalInfo
+	statuses      map[string]int
+	orders        map[string][]string
+	notifications []*notificationEntry
+	consumptions  map[string][]byte
+}
+
+type notificationEntry struct {
+	payload       *applepay.NotificationV2Payload
+	transactionId string
+	attempts      []*applepay.SendAttemptItem
+}
+
+// NewServer 生成测试证书链并启动服务，使用完毕后调用 Close
+func NewServer(issuer, bundleID, keyID string) (*Server, error) {
+	pki, err := NewPKI()
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		PKI:          pki,
+		Issuer:       issuer,
+		BundleID:     bundleID,
+		KeyID:        keyID,
+		Environment:  DefaultEnvironment,
+		PageSize:     DefaultPageSize,
+		transactions: make(map[string]*applepay.TransactionInfo),
+		renewals:     make(map[string]*applepay.RenewalInfo),
+		statuses:     make(map[string]int),
+		orders:       make(map[string][]string),
+		consumptions: make(map[string][]byte),
+	}
+	s.Server = httptest.NewServer(s.routes())
+	return s, nil
+}
+
+// NewApiClient 创建指向该服务的 ApiClient，信任该服务的根证书，PublicKey 为空时使用该私钥对应的公钥校验 token
+func (s *Server) NewApiClient(privateKey []byte) (*applepay.ApiClient, error) {
+	api, err := applepay.NewApiClient(s.Issuer, s.BundleID, s.KeyID, privateKey, false)
+	if err != nil {
+		return nil, err
+	}
+	api.Client.SetBaseURL(s.URL)
+	api.Verifier = s.PKI.Verifier()
+	if s.PublicKey == nil {
+		s.PublicKey = &api.PrivateKey.PublicKey
+	}
+	return api, nil
+}
+
+// NewApiClientWithSigner 创建使用 crypto.Signer 签名token的 ApiClient，PublicKey 为空时使用签名器的公钥
+func (s *Server) NewApiClientWithSigner(signer crypto.Signer) (*applepay.ApiClient, error) {
+	api, err := applepay.NewApiClientWithSigner(s.Issuer, s.BundleID, s.KeyID, signer, false)
+	if err != nil {
+		return nil, err
+	}
+	api.Client.SetBaseURL(s.URL)
+	api.Verifier = s.PKI.Verifier()
+	if s.PublicKey == nil {
+		pub, ok := signer.Public().(*ecdsa.PublicKey)
+		if !ok {
+			return nil, errors.New("signer public key must be ecdsa")
+		}
+		s.PublicKey = pub
+	}
+	return api, nil
+}
+
+// AddTransaction 添加交易，未设置的 bundleId、environment、originalTransactionId、signedDate 使用默认值
+func (s *Server) AddTransaction(ti *applepay.TransactionInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ti.BundleId == "" {
+		ti.BundleId = s.BundleID
+	}
+	if ti.Environment == "" {
+		ti.Environment = s.Environment
+	}
+	if ti.OriginalTransactionId == "" {
+		ti.OriginalTransactionId = ti.TransactionId
+	}
+	if ti.SignedDate == 0 {
+		ti.SignedDate = time.Now().UnixMilli()
+	}
+	s.transactions[ti.TransactionId] = ti
+}
+
+// SetRenewalInfo 设置自动续期订阅的续订信息，以 originalTransactionId 区分
+func (s *Server) SetRenewalInfo(ri *applepay.RenewalInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ri.Environment == "" {
+		ri.Environment = s.Environment
+	}
+	s.renewals[ri.OriginalTransactionId] = ri
+}
+
+// SetSubscriptionStatus 指定订阅状态 1:有效 2:过期 3:账单重试 4:宽限期 5:撤销，未指定时根据交易与续订信息计算
+func (s *Server) SetSubscriptionStatus(originalTransactionId string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[originalTransactionId] = status
+}
+
+// AddOrder 关联订单号与交易
+func (s *Server) AddOrder(orderId string, transactionIds ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[orderId] = append(s.orders[orderId], transactionIds...)
+}
+
+// AddNotification 添加通知历史，attempts 为空时视为一次成功的发送
+func (s *Server) AddNotification(payload *applepay.NotificationV2Payload, attempts ...*applepay.SendAttemptItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if payload.SignedDate == 0 {
+		payload.SignedDate = time.Now().UnixMilli()
+	}
+	if len(attempts) == 0 {
+		attempts = []*applepay.SendAttemptItem{{AttemptDate: payload.SignedDate, SendAttemptResult: "SUCCESS"}}
+	}
+	entry := &notificationEntry{payload: payload, attempts: attempts}
+	if payload.Data != nil && payload.Data.SignedTransactionInfo != "" {
+		ti := new(applepay.TransactionInfo)
+		if err := s.PKI.Verify(payload.Data.SignedTransactionInfo, ti); err == nil {
+			entry.transactionId = ti.TransactionId
+		}
+	}
+	s.notifications = append(s.notifications, entry)
+}
+
+// ConsumptionRequest 返回 Send Consumption Information 收到的请求体
+func (s *Server) ConsumptionRequest(transactionId string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	body, ok := s.consumptions[transactionId]
+	return body, ok
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/inApps/v1/transactions/consumption/", s.handleConsumption)
+	mux.HandleFunc("/inApps/v1/transactions/", s.handleTransactionInfo)
+	mux.HandleFunc("/inApps/v1/history/", s.handleTransactionHistory)
+	mux.HandleFunc("/inApps/v1/subscriptions/", s.handleSubscriptionStatuses)
+	mux.HandleFunc("/inApps/v1/lookup/", s.handleLookUpOrderId)
+	mux.HandleFunc("/inApps/v2/refund/lookup/", s.handleRefundHistory)
+	mux.HandleFunc("/inApps/v1/notifications/history", s.handleNotificationHistory)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// authorized 校验 bearer token
+// https://developer.apple.com/documentation/appstoreserverapi/generating_tokens_for_api_requests
+func (s *Server) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	tokenStr := strings.TrimPrefix(auth, "Bearer ")
+	claims := jwt2.MapClaims{}
+	var (
+		token *jwt2.Token
+		err   error
+	)
+	if s.PublicKey != nil {
+		token, err = jwt2.ParseWithClaims(tokenStr, claims, func(token *jwt2.Token) (any, error) {
+			return s.PublicKey, nil
+		})
+	} else {
+		token, _, err = new(jwt2.Parser).ParseUnverified(tokenStr, claims)
+	}
+	if err != nil {
+		return false
+	}
+	if token.Method.Alg() != jwt2.SigningMethodES256.Alg() || token.Header["kid"] != s.KeyID {
+		return false
+	}
+	if !claims.VerifyAudience(audience, true) || !claims.VerifyIssuer(s.Issuer, true) ||
+		!claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return false
+	}
+	bid, _ := claims["bid"].(string)
+	return bid == s.BundleID
+}
+
+func (s *Server) handleTransactionInfo(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	transactionId := strings.TrimPrefix(r.URL.Path, "/inApps/v1/transactions/")
+	if !validTransactionId(transactionId) {
+		writeError(w, ErrorCodeInvalidTransactionId)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ti, ok := s.transactions[transactionId]
+	if !ok {
+		writeError(w, ErrorCodeTransactionIdNotFound)
+		return
+	}
+	signed, err := s.PKI.Sign(ti)
+	if err != nil {
+		writeError(w, ErrorCodeGeneralInternal)
+		return
+	}
+	writeJSON(w, http.StatusOK, &applepay.ResponseTransactionInfo{SignedTransactionInfo: signed})
+}
+
+// handleTransactionHistory 与真实接口相同，返回该用户在app中所有商品的交易，不限于同一 originalTransactionId
+// 支持 productId、productType、subscriptionGroupIdentifier、startDate、endDate、inAppOwnershipType、revoked、sort 参数，以 revision 分页
+func (s *Server) handleTransactionHistory(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	transactionId := strings.TrimPrefix(r.URL.Path, "/inApps/v1/history/")
+	if !validTransactionId(transactionId) {
+		writeError(w, ErrorCodeInvalidTransactionId)
+		return
+	}
+	query := r.URL.Query()
+	offset, ok := parseOffset(query.Get("revision"))
+	if !ok {
+		writeError(w, ErrorCodeInvalidRequestRevision)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.transactions[transactionId]; !ok {
+		writeError(w, ErrorCodeTransactionIdNotFound)
+		return
+	}
+	list := s.transactionsWhere(func(t *applepay.TransactionInfo) bool {
+		return t.BundleId == s.BundleID && matchHistoryQuery(t, query)
+	})
+	if query.Get("sort") == "DESCENDING" {
+		reverse(list)
+	}
+	page, hasMore, err := s.signPage(list, offset)
+	if err != nil {
+		writeError(w, ErrorCodeGeneralInternal)
+		return
+	}
+	writeJSON(w, http.StatusOK, &applepay.ResponseTransactionHistory{
+		AppAppleId:         s.AppAppleID,
+		BundleId:           s.BundleID,
+		Environment:        s.Environment,
+		HasMore:            hasMore,
+		Revision:           strconv.Itoa(offset + len(page)),
+		SignedTransactions: page,
+	})
+}
+
+// handleSubscriptionStatuses 返回用户所有订阅组中每个 originalTransactionId 的最新交易，status 参数可以重复，按状态过滤
+func (s *Server) handleSubscriptionStatuses(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	transactionId := strings.TrimPrefix(r.URL.Path, "/inApps/v1/subscriptions/")
+	if !validTransactionId(transactionId) {
+		writeError(w, ErrorCodeInvalidTransactionId)
+		return
+	}
+	statuses := make(map[int]bool)
+	for _, v := range r.URL.Query()["status"] {
+		status, err := strconv.Atoi(v)
+		if err != nil || status < 1 || status > 5 {
+			writeError(w, ErrorCodeInvalidStatus)
+			return
+		}
+		statuses[status] = true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.transactions[transactionId]; !ok {
+		writeError(w, ErrorCodeOriginalTransactionIdNotFound)
+		return
+	}
+	// 升序遍历，后购买的交易覆盖同一 originalTransactionId 的旧交易
+	latest := make(map[string]*applepay.TransactionInfo)
+	var originals []string
+	for _, ti := range s.transactionsWhere(func(t *applepay.TransactionInfo) bool {
+		return t.BundleId == s.BundleID && isSubscription(t)
+	}) {
+		if _, ok := latest[ti.OriginalTransactionId]; !ok {
+			originals = append(originals, ti.OriginalTransactionId)
+		}
+		latest[ti.OriginalTransactionId] = ti
+	}
+	var data []*applepay.AllSubscriptionStatusesData
+	groups := make(map[string]*applepay.AllSubscriptionStatusesData)
+	for _, originalTransactionId := range originals {
+		ti := latest[originalTransactionId]
+		item := &applepay.LastTransactionsItem{
+			OriginalTransactionId: originalTransactionId,
+			Status:                s.subscriptionStatus(ti),
+		}
+		if len(statuses) > 0 && !statuses[item.Status] {
+			continue
+		}
+		var err error
+		if item.SignedTransactionInfo, err = s.PKI.Sign(ti); err != nil {
+			writeError(w, ErrorCodeGeneralInternal)
+			return
+		}
+		if ri, ok := s.renewals[originalTransactionId]; ok {
+			if item.SignedRenewalInfo, err = s.PKI.Sign(ri); err != nil {
+				writeError(w, ErrorCodeGeneralInternal)
+				return
+			}
+		}
+		group, ok := groups[ti.SubscriptionGroupIdentifier]
+		if !ok {
+			group = &applepay.AllSubscriptionStatusesData{SubscriptionGroupIdentifier: ti.SubscriptionGroupIdentifier}
+			groups[ti.SubscriptionGroupIdentifier] = group
+			data = append(data, group)
+		}
+		group.LastTransactions = append(group.LastTransactions, item)
+	}
+	writeJSON(w, http.StatusOK, &applepay.ResponseAllSubscriptionStatuses{
+		AppAppleId:  s.AppAppleID,
+		BundleId:    s.BundleID,
+		Environment: s.Environment,
+		Data:        data,
+	})
+}
+
+// isSubscription 自动续期订阅，没有类型时根据订阅组判断
+func isSubscription(ti *applepay.TransactionInfo) bool {
+	if ti.Type == "" {
+		return ti.SubscriptionGroupIdentifier != "" || ti.ExpiresDate > 0
+	}
+	return ti.Type == productTypes["AUTO_RENEWABLE"]
+}
+
+// subscriptionStatus 1:有效 2:过期 3:账单重试 4:宽限期 5:撤销
+func (s *Server) subscriptionStatus(ti *applepay.TransactionInfo) int {
+	if status, ok := s.statuses[ti.OriginalTransactionId]; ok {
+		return status
+	}
+	now := time.Now().UnixMilli()
+	switch {
+	case ti.RevocationDate > 0:
+		return 5
+	case ti.ExpiresDate > now:
+		return 1
+	}
+	if ri, ok := s.renewals[ti.OriginalTransactionId]; ok && ri.IsInBillingRetryPeriod {
+		if ri.GracePeriodExpiresDate > now {
+			return 4
+		}
+		return 3
+	}
+	return 2
+}
+
+func (s *Server) handleConsumption(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPut) {
+		return
+	}
+	transactionId := strings.TrimPrefix(r.URL.Path, "/inApps/v1/transactions/consumption/")
+	if !validTransactionId(transactionId) {
+		writeError(w, ErrorCodeInvalidTransactionId)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, ErrorCodeGeneralBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.transactions[transactionId]; !ok {
+		writeError(w, ErrorCodeTransactionIdNotFound)
+		return
+	}
+	s.consumptions[transactionId] = body
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleLookUpOrderId(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	orderId := strings.TrimPrefix(r.URL.Path, "/inApps/v1/lookup/")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	transactionIds, ok := s.orders[orderId]
+	if !ok {
+		writeJSON(w, http.StatusOK, &applepay.ResponseLookUpOrderId{Status: 1})
+		return
+	}
+	resp := &applepay.ResponseLookUpOrderId{}
+	for _, id := range transactionIds {
+		ti, ok := s.transactions[id]
+		if !ok {
+			continue
+		}
+		signed, err := s.PKI.Sign(ti)
+		if err != nil {
+			writeError(w, ErrorCodeGeneralInternal)
+			return
+		}
+		resp.SignedTransactions = append(resp.SignedTransactions, applepay.SignedTransaction(signed))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleRefundHistory 与真实接口相同，返回该用户在app中所有商品的已撤销交易
+func (s *Server) handleRefundHistory(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	transactionId := strings.TrimPrefix(r.URL.Path, "/inApps/v2/refund/lookup/")
+	if !validTransactionId(transactionId) {
+		writeError(w, ErrorCodeInvalidTransactionId)
+		return
+	}
+	offset, ok := parseOffset(r.URL.Query().Get("revision"))
+	if !ok {
+		writeError(w, ErrorCodeInvalidRequestRevision)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.transactions[transactionId]; !ok {
+		writeError(w, ErrorCodeTransactionIdNotFound)
+		return
+	}
+	list := s.transactionsWhere(func(t *applepay.TransactionInfo) bool {
+		return t.BundleId == s.BundleID && t.RevocationDate > 0
+	})
+	page, hasMore, err := s.signPage(list, offset)
+	if err != nil {
+		writeError(w, ErrorCodeGeneralInternal)
+		return
+	}
+	writeJSON(w, http.StatusOK, &applepay.ResponseRefundHistory{
+		HasMore:            hasMore,
+		Revision:           strconv.Itoa(offset + len(page)),
+		SignedTransactions: page,
+	})
+}
+
+func (s *Server) handleNotificationHistory(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	offset, ok := parseOffset(r.URL.Query().Get("paginationToken"))
+	if !ok {
+		writeError(w, ErrorCodeInvalidPaginationToken)
+		return
+	}
+	req := new(applepay.NotificationHistoryRequest)
+	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+		if err := json.Unmarshal(body, req); err != nil {
+			writeError(w, ErrorCodeGeneralBadRequest)
+			return
+		}
+	}
+	if req.StartDate > 0 && req.EndDate > 0 && req.StartDate > req.EndDate {
+		writeError(w, ErrorCodeInvalidEndDate)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var list []*notificationEntry
+	for _, entry := range s.notifications {
+		if matchNotification(entry, req) {
+			list = append(list, entry)
+		}
+	}
+	if offset > len(list) {
+		writeError(w, ErrorCodeInvalidPaginationToken)
+		return
+	}
+	end := offset + s.pageSize()
+	if end > len(list) {
+		end = len(list)
+	}
+	resp := &applepay.ResponseNotificationHistory{HasMore: end < len(list)}
+	for _, entry := range list[offset:end] {
+		signed, err := s.PKI.Sign(entry.payload)
+		if err != nil {
+			writeError(w, ErrorCodeGeneralInternal)
+			return
+		}
+		resp.NotificationHistory = append(resp.NotificationHistory, &applepay.NotificationItem{
+			SendAttempts:  entry.attempts,
+			SignedPayload: signed,
+		})
+	}
+	if resp.HasMore {
+		resp.PaginationToken = strconv.Itoa(end)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func matchNotification(entry *notificationEntry, req *applepay.NotificationHistoryRequest) bool {
+	p := entry.payload
+	switch {
+	case req.StartDate > 0 && p.SignedDate < req.StartDate,
+		req.EndDate > 0 && p.SignedDate >= req.EndDate,
+		req.NotificationType != "" && p.NotificationType != req.NotificationType,
+		req.NotificationSubtype != "" && p.Subtype != req.NotificationSubtype,
+		req.TransactionId != "" && entry.transactionId != req.TransactionId:
+		return false
+	}
+	if req.OnlyFailures {
+		last := entry.attempts[len(entry.attempts)-1]
+		return last.SendAttemptResult != "SUCCESS"
+	}
+	return true
+}
+
+// transactionsOf 按购买时间升序返回同一 originalTransactionId 的交易
+func (s *Server) transactionsOf(originalTransactionId string, filter func(*applepay.TransactionInfo) bool) []*applepay.TransactionInfo {
+	return s.transactionsWhere(func(ti *applepay.TransactionInfo) bool {
+		return ti.OriginalTransactionId == originalTransactionId && (filter == nil || filter(ti))
+	})
+}
+
+// transactionsWhere 按购买时间升序返回满足条件的交易
+func (s *Server) transactionsWhere(filter func(*applepay.TransactionInfo) bool) []*applepay.TransactionInfo {
+	var list []*applepay.TransactionInfo
+	for _, ti := range s.transactions {
+		if filter(ti) {
+			list = append(list, ti)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].PurchaseDate == list[j].PurchaseDate {
+			return list[i].TransactionId < list[j].TransactionId
+		}
+		return list[i].PurchaseDate < list[j].PurchaseDate
+	})
+	return list
+}
+
+// productTypes Get Transaction History 的 productType 参数对应的交易类型
+var productTypes = map[string]string{
+	"AUTO_RENEWABLE": "Auto-Renewable Subscription",
+	"NON_RENEWABLE":  "Non-Renewing Subscription",
+	"CONSUMABLE":     "Consumable",
+	"NON_CONSUMABLE": "Non-Consumable",
+}
+
+func matchHistoryQuery(ti *applepay.TransactionInfo, query url.Values) bool {
+	if productIds := query["productId"]; len(productIds) > 0 && !contains(productIds, ti.ProductId) {
+		return false
+	}
+	if types := query["productType"]; len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			matched = matched || productTypes[t] == ti.Type
+		}
+		if !matched {
+			return false
+		}
+	}
+	if groups := query["subscriptionGroupIdentifier"]; len(groups) > 0 && !contains(groups, ti.SubscriptionGroupIdentifier) {
+		return false
+	}
+	if start, err := strconv.ParseInt(query.Get("startDate"), 10, 64); err == nil && ti.PurchaseDate < start {
+		return false
+	}
+	if end, err := strconv.ParseInt(query.Get("endDate"), 10, 64); err == nil && ti.PurchaseDate >= end {
+		return false
+	}
+	if ownership := query.Get("inAppOwnershipType"); ownership != "" && ownership != ti.InAppOwnershipType {
+		return false
+	}
+	if revoked := query.Get("revoked"); revoked != "" && (ti.RevocationDate > 0) != (revoked == "true") {
+		return false
+	}
+	return true
+}
+
+func (s *Server) signPage(list []*applepay.TransactionInfo, offset int) (page []applepay.SignedTransaction, hasMore bool, err error) {
+	if offset > len(list) {
+		offset = len(list)
+	}
+	end := offset + s.pageSize()
+	if end > len(list) {
+		end = len(list)
+	}
+	page = []applepay.SignedTransaction{}
+	for _, ti := range list[offset:end] {
+		signed, err := s.PKI.Sign(ti)
+		if err != nil {
+			return nil, false, err
+		}
+		page = append(page, applepay.SignedTransaction(signed))
+	}
+	return page, end < len(list), nil
+}
+
+func (s *Server) pageSize() int {
+	if s.PageSize <= 0 {
+		return DefaultPageSize
+	}
+	return s.PageSize
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+// validTransactionId 交易ID为纯数字
+func validTransactionId(transactionId string) bool {
+	if transactionId == "" {
+		return false
+	}
+	_, err := strconv.ParseUint(transactionId, 10, 64)
+	return err == nil
+}
+
+// parseOffset 解析 revision、paginationToken，为空时从头开始
+func parseOffset(s string) (int, bool) {
+	if s == "" {
+		return 0, true
+	}
+	offset, err := strconv.Atoi(s)
+	if err != nil || offset < 0 {
+		return 0, false
+	}
+	return offset, true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func reverse(list []*applepay.TransactionInfo) {
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+}
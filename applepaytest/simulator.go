@@ -0,0 +1,348 @@
+package applepaytest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pkg6/applego/applepay"
+	"github.com/pkg6/applego/utility"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultSimulationPeriod 模拟订阅的续订周期
+	DefaultSimulationPeriod = 30 * 24 * time.Hour
+	// DefaultSimulationGracePeriod 模拟订阅的宽限期
+	DefaultSimulationGracePeriod = 16 * 24 * time.Hour
+)
+
+// SimulationState 模拟过程中的交易与续订信息，每一步在上一步的基础上修改
+type SimulationState struct {
+	Transaction *applepay.TransactionInfo
+	// Renewal 非自动续期订阅为nil
+	Renewal *applepay.RenewalInfo
+	// Status 订阅状态 1:有效 2:过期 3:账单重试 4:宽限期 5:撤销，非自动续期订阅为0
+	Status int
+	// Now 模拟的当前时间，作为通知与交易的签名时间
+	Now time.Time
+
+	period      time.Duration
+	gracePeriod time.Duration
+	nextId      int64
+}
+
+// Renew 生成下一期的续订交易，并把模拟时间推进到上一期的过期时间
+func (st *SimulationState) Renew() {
+	ti := st.Transaction
+	st.Now = time.UnixMilli(ti.ExpiresDate)
+	ti.TransactionId = st.newId()
+	ti.WebOrderLineItemId = st.newId()
+	ti.PurchaseDate = ti.ExpiresDate
+	ti.ExpiresDate = st.Now.Add(st.period).UnixMilli()
+	ti.OfferType = 0
+	ti.OfferIdentifier = ""
+	ti.TransactionReason = "RENEWAL"
+	if st.Renewal != nil {
+		st.Renewal.RenewalDate = ti.ExpiresDate
+		st.Renewal.OfferType = 0
+	}
+	st.Status = 1
+}
+
+func (st *SimulationState) newId() string {
+	st.nextId++
+	return strconv.FormatInt(st.nextId, 10)
+}
+
+// ScenarioStep 场景中的一条通知，Apply 在发送前修改模拟状态
+type ScenarioStep struct {
+	NotificationType string
+	Subtype          string
+	Apply            func(st *SimulationState)
+}
+
+// Scenario 一组按顺序发送的通知
+type Scenario struct {
+	Name string
+	// Type 商品类型：Auto-Renewable Subscription、Non-Consumable、Consumable、Non-Renewing Subscription
+	Type  string
+	Steps []ScenarioStep
+}
+
+var (
+	// ScenarioSubscriptionLifecycle 试用 -> 续订 -> 扣费失败进入宽限期 -> 宽限期结束 -> 账单重试失败过期
+	ScenarioSubscriptionLifecycle = &Scenario{
+		Name: "subscription-lifecycle",
+		Type: "Auto-Renewable Subscription",
+		Steps: []ScenarioStep{
+			{NotificationType: applepay.NotificationTypeSubscribed, Subtype: applepay.SubtypeInitialBuy, Apply: func(st *SimulationState) {
+				st.Transaction.OfferType = 1
+				st.Renewal.OfferType = 1
+			}},
+			{NotificationType: applepay.NotificationTypeDidRenew, Apply: func(st *SimulationState) {
+				st.Renew()
+			}},
+			{NotificationType: applepay.NotificationTypeDidFailToRenew, Subtype: applepay.SubtypeGracePeriod, Apply: func(st *SimulationState) {
+				st.Now = time.UnixMilli(st.Transaction.ExpiresDate)
+				st.Renewal.IsInBillingRetryPeriod = true
+				st.Renewal.GracePeriodExpiresDate = st.Now.Add(st.gracePeriod).UnixMilli()
+				st.Status = 4
+			}},
+			{NotificationType: applepay.NotificationTypeGracePeriodExpired, Apply: func(st *SimulationState) {
+				st.Now = time.UnixMilli(st.Renewal.GracePeriodExpiresDate)
+				st.Status = 3
+			}},
+			{NotificationType: applepay.NotificationTypeExpired, Subtype: applepay.SubtypeBillingRetry, Apply: func(st *SimulationState) {
+				st.Now = st.Now.Add(st.period)
+				st.Renewal.IsInBillingRetryPeriod = false
+				st.Renewal.AutoRenewStatus = 0
+				st.Renewal.ExpirationIntent = 2
+				st.Status = 2
+			}},
+		},
+	}
+	// ScenarioSubscriptionCancel 订阅 -> 关闭自动续订 -> 到期后过期
+	ScenarioSubscriptionCancel = &Scenario{
+		Name: "subscription-cancel",
+		Type: "Auto-Renewable Subscription",
+		Steps: []ScenarioStep{
+			{NotificationType: applepay.NotificationTypeSubscribed, Subtype: applepay.SubtypeInitialBuy},
+			{NotificationType: applepay.NotificationTypeDidChangeRenewalStatus, Subtype: applepay.SubtypeAutoRenewDisabled, Apply: func(st *SimulationState) {
+				st.Now = st.Now.Add(st.period / 2)
+				st.Renewal.AutoRenewStatus = 0
+			}},
+			{NotificationType: applepay.NotificationTypeExpired, Subtype: applepay.SubtypeVoluntary, Apply: func(st *SimulationState) {
+				st.Now = time.UnixMilli(st.Transaction.ExpiresDate)
+				st.Renewal.ExpirationIntent = 1
+				st.Status = 2
+			}},
+		},
+	}
+	// ScenarioPurchaseRefund 购买消耗型商品 -> 退款
+	ScenarioPurchaseRefund = &Scenario{
+		Name: "purchase-refund",
+		Type: "Consumable",
+		Steps: []ScenarioStep{
+			{NotificationType: applepay.NotificationTypeOneTimeCharge},
+			{NotificationType: applepay.NotificationTypeRefund, Apply: func(st *SimulationState) {
+				st.Now = st.Now.Add(24 * time.Hour)
+				st.Transaction.RevocationDate = st.Now.UnixMilli()
+				st.Transaction.RevocationReason = 0
+			}},
+		},
+	}
+)
+
+// Scenarios 内置场景，以名称区分
+var Scenarios = map[string]*Scenario{
+	ScenarioSubscriptionLifecycle.Name: ScenarioSubscriptionLifecycle,
+	ScenarioSubscriptionCancel.Name:    ScenarioSubscriptionCancel,
+	ScenarioPurchaseRefund.Name:        ScenarioPurchaseRefund,
+}
+
+// Simulator 使用测试证书链生成通知，并按顺序POST到本地webhook
+// webhook 需要使用 pki.Verifier() 或 applepay.NewSignedDataVerifier(rootPEM) 校验签名
+type Simulator struct {
+	PKI        *PKI
+	WebhookURL string
+	Client     *http.Client
+	// Interval 两次通知之间的等待时间
+	Interval    time.Duration
+	BundleID    string
+	ProductID   string
+	Environment string
+	AppAppleID  int
+	// Period GracePeriod 模拟的订阅周期与宽限期
+	Period      time.Duration
+	GracePeriod time.Duration
+	// Start 模拟开始时间，默认当前时间
+	Start time.Time
+}
+
+// SimulationResult 一条通知的发送结果
+type SimulationResult struct {
+	NotificationType string
+	Subtype          string
+	NotificationUUID string
+	SignedPayload    string
+	StatusCode       int
+	Duration         time.Duration
+	Err              error
+}
+
+func NewSimulator(pki *PKI, webhookURL string) *Simulator {
+	return &Simulator{
+		PKI:         pki,
+		WebhookURL:  webhookURL,
+		BundleID:    "com.example.app",
+		ProductID:   "com.example.product",
+		Environment: DefaultEnvironment,
+	}
+}
+
+// Payloads 按场景生成通知，不发送
+func (s *Simulator) Payloads(scenario *Scenario) ([]*applepay.NotificationV2Payload, error) {
+	if len(scenario.Steps) == 0 {
+		return nil, errors.New("scenario has no steps")
+	}
+	st := s.newState(scenario)
+	payloads := make([]*applepay.NotificationV2Payload, 0, len(scenario.Steps))
+	for i, step := range scenario.Steps {
+		if step.Apply != nil {
+			step.Apply(st)
+		}
+		payload, err := s.payload(st, step)
+		if err != nil {
+			return nil, fmt.Errorf("step %d %s: %w", i, step.NotificationType, err)
+		}
+		payloads = append(payloads, payload)
+	}
+	return payloads, nil
+}
+
+// Run 依次签名并发送场景中的通知，webhook 响应非2xx时记录在结果中并继续发送
+func (s *Simulator) Run(ctx context.Context, scenario *Scenario) ([]*SimulationResult, error) {
+	payloads, err := s.Payloads(scenario)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*SimulationResult, 0, len(payloads))
+	for i, payload := range payloads {
+		if i > 0 && s.Interval > 0 {
+			select {
+			case <-ctx.Done():
+				return results, ctx.Err()
+			case <-time.After(s.Interval):
+			}
+		}
+		signedPayload, err := s.PKI.SignNotification(payload)
+		if err != nil {
+			return results, err
+		}
+		result := &SimulationResult{
+			NotificationType: payload.NotificationType,
+			Subtype:          payload.Subtype,
+			NotificationUUID: payload.NotificationUUID,
+			SignedPayload:    signedPayload,
+		}
+		start := time.Now()
+		result.StatusCode, result.Err = s.post(ctx, signedPayload)
+		result.Duration = time.Since(start)
+		results = append(results, result)
+		if err = ctx.Err(); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func (s *Simulator) post(ctx context.Context, signedPayload string) (int, error) {
+	body, err := json.Marshal(&applepay.NotificationV2Request{SignedPayload: signedPayload})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (s *Simulator) newState(scenario *Scenario) *SimulationState {
+	now := s.Start
+	if now.IsZero() {
+		now = time.Now()
+	}
+	st := &SimulationState{
+		Now:         now,
+		period:      s.Period,
+		gracePeriod: s.GracePeriod,
+		nextId:      now.UnixMilli() * 1000,
+	}
+	if st.period <= 0 {
+		st.period = DefaultSimulationPeriod
+	}
+	if st.gracePeriod <= 0 {
+		st.gracePeriod = DefaultSimulationGracePeriod
+	}
+	transactionId := st.newId()
+	st.Transaction = &applepay.TransactionInfo{
+		TransactionId:         transactionId,
+		OriginalTransactionId: transactionId,
+		BundleId:              s.BundleID,
+		ProductId:             s.ProductID,
+		Environment:           s.Environment,
+		PurchaseDate:          now.UnixMilli(),
+		OriginalPurchaseDate:  now.UnixMilli(),
+		Quantity:              1,
+		Type:                  scenario.Type,
+		InAppOwnershipType:    "PURCHASED",
+		TransactionReason:     "PURCHASE",
+		Storefront:            "USA",
+		StorefrontId:          "143441",
+	}
+	if scenario.Type == "Auto-Renewable Subscription" {
+		st.Transaction.WebOrderLineItemId = st.newId()
+		st.Transaction.SubscriptionGroupIdentifier = "20000000"
+		st.Transaction.ExpiresDate = now.Add(st.period).UnixMilli()
+		st.Renewal = &applepay.RenewalInfo{
+			AutoRenewProductId:          s.ProductID,
+			AutoRenewStatus:             1,
+			Environment:                 s.Environment,
+			OriginalTransactionId:       transactionId,
+			ProductId:                   s.ProductID,
+			RecentSubscriptionStartDate: now.UnixMilli(),
+			RenewalDate:                 st.Transaction.ExpiresDate,
+		}
+		st.Status = 1
+	}
+	return st
+}
+
+func (s *Simulator) payload(st *SimulationState, step ScenarioStep) (*applepay.NotificationV2Payload, error) {
+	signedDate := st.Now.UnixMilli()
+	ti := *st.Transaction
+	ti.SignedDate = signedDate
+	var ri *applepay.RenewalInfo
+	if st.Renewal != nil {
+		renewal := *st.Renewal
+		renewal.SignedDate = signedDate
+		ri = &renewal
+	}
+	data, err := s.PKI.NotificationData(&ti, ri)
+	if err != nil {
+		return nil, err
+	}
+	data.AppAppleID = s.AppAppleID
+	data.BundleVersion = "1"
+	data.Status = st.Status
+	uuid, err := utility.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &applepay.NotificationV2Payload{
+		NotificationType: step.NotificationType,
+		Subtype:          step.Subtype,
+		NotificationUUID: uuid,
+		Version:          "2.0",
+		SignedDate:       signedDate,
+		Data:             data,
+	}, nil
+}
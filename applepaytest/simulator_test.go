@@ -0,0 +1,93 @@
+package applepaytest
+
+import (
+	"context"
+	"github.com/pkg6/applego/applepay"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestSimulatorRun(t *testing.T) {
+	pki, err := NewPKI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu       sync.Mutex
+		received []*applepay.NotificationV2SignedPayloadResponse
+	)
+	handler := applepay.NewNotificationV2Handler(func(ctx context.Context, n *applepay.NotificationV2SignedPayloadResponse) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, n)
+		return nil
+	})
+	handler.Decode = func(signedPayload string) (*applepay.NotificationV2SignedPayloadResponse, error) {
+		return pki.Verifier().DecodeNotificationV2(signedPayload, &applepay.NotificationV2DecodeOptions{AllowMissing: true})
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	results, err := NewSimulator(pki, server.URL).Run(context.Background(), ScenarioSubscriptionLifecycle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(results), len(ScenarioSubscriptionLifecycle.Steps))
+	for _, r := range results {
+		assert.Equal(t, r.Err, nil)
+		assert.Equal(t, r.StatusCode, 200)
+	}
+	assert.Equal(t, len(received), 5)
+	var statuses []int
+	for _, n := range received {
+		statuses = append(statuses, n.Payload.Data.Status)
+	}
+	assert.Equal(t, statuses, []int{1, 1, 4, 3, 2})
+	assert.Equal(t, received[0].TransactionInfo.OfferType, int64(1))
+	assert.NotEqual(t, received[1].TransactionInfo.TransactionId, received[0].TransactionInfo.TransactionId)
+	assert.Equal(t, received[1].TransactionInfo.OriginalTransactionId, received[0].TransactionInfo.TransactionId)
+	assert.Equal(t, received[2].RenewalInfo.IsInBillingRetryPeriod, true)
+	assert.Equal(t, received[4].RenewalInfo.ExpirationIntent, int64(2))
+}
+
+func TestSimulatorPurchaseRefund(t *testing.T) {
+	pki, err := NewPKI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloads, err := NewSimulator(pki, "").Payloads(ScenarioPurchaseRefund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, payloads[1].NotificationType, applepay.NotificationTypeRefund)
+	assert.Equal(t, payloads[1].Data.SignedRenewalInfo, "")
+	ti, err := pki.Verifier().DecodeTransactionInfo(payloads[1].Data.SignedTransactionInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ti.Type, "Consumable")
+	assert.Equal(t, ti.RevocationDate, payloads[1].SignedDate)
+}
+
+func TestLoadPKI(t *testing.T) {
+	pki, err := NewPKI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err = pki.WriteFiles(dir); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadPKI(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := loaded.SignTransaction(&applepay.TransactionInfo{TransactionId: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pki.Verifier().DecodeTransactionInfo(signed)
+	assert.Equal(t, err, nil)
+}
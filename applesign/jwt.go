@@ -1,6 +1,7 @@
 package applesign
 
 import (
+	"crypto"
 	jwt2 "github.com/golang-jwt/jwt"
 	"github.com/pkg6/applego/jwt"
 	"github.com/pkg6/applego/utility"
@@ -18,8 +19,13 @@ func GenerateClientSecret(signingKey, teamID, clientID, keyID string) (string, e
 	if err != nil {
 		return "", err
 	}
+	return GenerateClientSecretWithSigner(privKey, teamID, clientID, keyID)
+}
+
+// GenerateClientSecretWithSigner 使用 crypto.Signer 生成客户端机密，私钥可以保存在 HSM/KMS 中
+func GenerateClientSecretWithSigner(signer crypto.Signer, teamID, clientID, keyID string) (string, error) {
 	now := time.Now()
-	return jwt.Encode(privKey, jwt2.SigningMethodES256, &jwt2.StandardClaims{
+	return jwt.EncodeWithSigner(signer, &jwt2.StandardClaims{
 		Issuer:   teamID,
 		IssuedAt: now.Unix(),
 		// 180 days
@@ -0,0 +1,103 @@
+// applego-simulator 使用测试证书链生成 App Store Server Notifications V2 并发送到本地webhook
+//
+//	applego-simulator -url http://localhost:8080/apple/notification -scenario subscription-lifecycle -interval 2s
+//
+// 证书链保存在 -pki 目录中，webhook 使用 applepay.NewSignedDataVerifier(root.pem) 校验签名
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/pkg6/applego/applepaytest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+func main() {
+	var (
+		url         = flag.String("url", "", "webhook url")
+		scenario    = flag.String("scenario", applepaytest.ScenarioSubscriptionLifecycle.Name, "scenario name, use -list to show all")
+		list        = flag.Bool("list", false, "list scenarios")
+		interval    = flag.Duration("interval", time.Second, "wait between notifications")
+		pkiDir      = flag.String("pki", ".applepaytest", "directory of the test certificate chain, created if missing")
+		bundleID    = flag.String("bundle-id", "com.example.app", "bundle id")
+		productID   = flag.String("product-id", "com.example.product", "product id")
+		environment = flag.String("env", applepaytest.DefaultEnvironment, "environment")
+	)
+	flag.Parse()
+	if *list {
+		names := make([]string, 0, len(applepaytest.Scenarios))
+		for name := range applepaytest.Scenarios {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return
+	}
+	sc, ok := applepaytest.Scenarios[*scenario]
+	if !ok {
+		fatalf("unknown scenario %q", *scenario)
+	}
+	if *url == "" {
+		fatalf("-url is required")
+	}
+	pki, err := loadOrCreatePKI(*pkiDir)
+	if err != nil {
+		fatalf("pki: %v", err)
+	}
+	fmt.Printf("root certificate: %s\n", filepath.Join(*pkiDir, applepaytest.RootCertFile))
+
+	sim := applepaytest.NewSimulator(pki, *url)
+	sim.Interval = *interval
+	sim.BundleID = *bundleID
+	sim.ProductID = *productID
+	sim.Environment = *environment
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	results, err := sim.Run(ctx, sc)
+	failed := 0
+	for _, r := range results {
+		name := r.NotificationType
+		if r.Subtype != "" {
+			name += "/" + r.Subtype
+		}
+		status := "ok"
+		if r.Err != nil {
+			status = r.Err.Error()
+			failed++
+		}
+		fmt.Printf("%-40s %3d %8s %s %s\n", name, r.StatusCode, r.Duration.Round(time.Millisecond), r.NotificationUUID, status)
+	}
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func loadOrCreatePKI(dir string) (*applepaytest.PKI, error) {
+	pki, err := applepaytest.LoadPKI(dir)
+	if err == nil {
+		return pki, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if pki, err = applepaytest.NewPKI(); err != nil {
+		return nil, err
+	}
+	return pki, pki.WriteFiles(dir)
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "applego-simulator: "+format+"\n", args...)
+	os.Exit(2)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/pkg6/applego/applepay"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+type apiFunc func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error)
+
+// apiEndpoint api 的子命令，args 为位置参数，flags 注册子命令参数并返回执行函数
+type apiEndpoint struct {
+	name  string
+	args  string
+	usage string
+	flags func(fs *flag.FlagSet) apiFunc
+}
+
+var apiEndpoints = []*apiEndpoint{
+	{"transaction", "<transactionId>", "Get Transaction Info", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			return api.GetTransactionInfo(args[0])
+		}
+	}},
+	{"history", "<transactionId>", "Get Transaction History", func(fs *flag.FlagSet) apiFunc {
+		revision := fs.String("revision", "", "revision from the previous page")
+		sort := fs.String("sort", "", "ASCENDING or DESCENDING")
+		productIds := fs.String("product-id", "", "comma separated product ids")
+		all := fs.Bool("all", false, "follow hasMore and return all pages")
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			query := url.Values{}
+			if *sort != "" {
+				query.Set("sort", *sort)
+			}
+			if *productIds != "" {
+				query["productId"] = strings.Split(*productIds, ",")
+			}
+			if *revision != "" {
+				query.Set("revision", *revision)
+			}
+			resp, err := api.GetTransactionHistory(args[0], query)
+			if err != nil || !*all {
+				return resp, err
+			}
+			for page := resp; page.HasMore; {
+				query.Set("revision", page.Revision)
+				if page, err = api.GetTransactionHistory(args[0], query); err != nil {
+					return nil, err
+				}
+				resp.SignedTransactions = append(resp.SignedTransactions, page.SignedTransactions...)
+				resp.Revision, resp.HasMore = page.Revision, page.HasMore
+			}
+			return resp, nil
+		}
+	}},
+	{"subscriptions", "<transactionId>", "Get All Subscription Statuses", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			return api.GetAllSubscriptionStatuses(args[0])
+		}
+	}},
+	{"consumption", "<transactionId>", "Send Consumption Information", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			if err := api.SendConsumptionInformation(args[0]); err != nil {
+				return nil, err
+			}
+			return map[string]any{"transactionId": args[0], "accepted": true}, nil
+		}
+	}},
+	{"lookup", "<orderId>", "Look Up Order ID", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			return api.LookUpOrderId(args[0])
+		}
+	}},
+	{"refunds", "<transactionId>", "Get Refund History", func(fs *flag.FlagSet) apiFunc {
+		revision := fs.String("revision", "", "revision from the previous page")
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			return api.GetRefundHistory(args[0], *revision)
+		}
+	}},
+	{"notifications", "", "Get Notification History, one page", func(fs *flag.FlagSet) apiFunc {
+		token := fs.String("token", "", "pagination token from the previous page")
+		req := notificationHistoryFlags(fs)
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			r, err := req()
+			if err != nil {
+				return nil, err
+			}
+			return api.GetNotificationHistoryWithRequest(*token, r)
+		}
+	}},
+	{"retention-images", "", "Retention Messaging: Get Image List", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			return api.GetRetentionImageList()
+		}
+	}},
+	{"retention-messages", "", "Retention Messaging: Get Message List", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			return api.GetRetentionMessageList()
+		}
+	}},
+	{"retention-image-upload", "<imageIdentifier> <file|->", "Retention Messaging: Upload Image, PNG", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			image, err := readFile(args[1], stdin)
+			if err != nil {
+				return nil, err
+			}
+			if err = api.UploadRetentionImage(args[0], image); err != nil {
+				return nil, err
+			}
+			return map[string]any{"imageIdentifier": args[0], "accepted": true}, nil
+		}
+	}},
+	{"retention-image-delete", "<imageIdentifier>", "Retention Messaging: Delete Image", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			if err := api.DeleteRetentionImage(args[0]); err != nil {
+				return nil, err
+			}
+			return map[string]any{"imageIdentifier": args[0], "deleted": true}, nil
+		}
+	}},
+	{"retention-message-upload", "<messageIdentifier>", "Retention Messaging: Upload Message", func(fs *flag.FlagSet) apiFunc {
+		header := fs.String("header", "", "message header")
+		body := fs.String("body", "", "message body")
+		imageId := fs.String("image-id", "", "uploaded image identifier")
+		altText := fs.String("alt-text", "", "image alternative text")
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			if *header == "" || *body == "" {
+				return nil, &usageError{"-header and -body are required"}
+			}
+			message := &applepay.RetentionMessage{Header: *header, Body: *body}
+			if *imageId != "" {
+				message.Image = &applepay.RetentionMessageImage{ImageIdentifier: *imageId, AltText: *altText}
+			}
+			if err := api.UploadRetentionMessage(args[0], message); err != nil {
+				return nil, err
+			}
+			return map[string]any{"messageIdentifier": args[0], "accepted": true}, nil
+		}
+	}},
+	{"retention-message-delete", "<messageIdentifier>", "Retention Messaging: Delete Message", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			if err := api.DeleteRetentionMessage(args[0]); err != nil {
+				return nil, err
+			}
+			return map[string]any{"messageIdentifier": args[0], "deleted": true}, nil
+		}
+	}},
+	{"retention-default-message", "<productId> <locale> <messageIdentifier>", "Retention Messaging: Configure Default Message", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			if err := api.ConfigureDefaultRetentionMessage(args[0], args[1], args[2]); err != nil {
+				return nil, err
+			}
+			return map[string]any{"productId": args[0], "locale": args[1], "messageIdentifier": args[2], "accepted": true}, nil
+		}
+	}},
+	{"retention-default-message-delete", "<productId> <locale>", "Retention Messaging: Delete Default Message", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			if err := api.DeleteDefaultRetentionMessage(args[0], args[1]); err != nil {
+				return nil, err
+			}
+			return map[string]any{"productId": args[0], "locale": args[1], "deleted": true}, nil
+		}
+	}},
+	{"external-purchase-report", "<requestIdentifier>", "Retrieve External Purchase Report", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			return api.RetrieveExternalPurchaseReport(args[0])
+		}
+	}},
+	{"external-purchase-report-send", "<report.json|@file|->", "Send External Purchase Report, ExternalPurchaseReport JSON", func(fs *flag.FlagSet) apiFunc {
+		return func(api *applepay.ApiClient, args []string, stdin io.Reader) (any, error) {
+			input, err := readInput(args[0], stdin)
+			if err != nil {
+				return nil, err
+			}
+			report := new(applepay.ExternalPurchaseReport)
+			if err = json.Unmarshal([]byte(input), report); err != nil {
+				return nil, fmt.Errorf("parse report: %w", err)
+			}
+			return api.SendExternalPurchaseReport(report)
+		}
+	}},
+}
+
+func runApi(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		printApiUsage()
+		return &usageError{"missing endpoint"}
+	}
+	var endpoint *apiEndpoint
+	for _, e := range apiEndpoints {
+		if e.name == args[0] {
+			endpoint = e
+		}
+	}
+	if endpoint == nil {
+		printApiUsage()
+		return &usageError{fmt.Sprintf("unknown endpoint %q", args[0])}
+	}
+	fs := newFlagSet("api " + endpoint.name)
+	creds := new(credentials)
+	creds.register(fs)
+	decode := fs.Bool("decode", false, "verify and decode signed fields in the response")
+	fn := endpoint.flags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != len(strings.Fields(endpoint.args)) {
+		return &usageError{fmt.Sprintf("usage: applego api %s [flags] %s", endpoint.name, endpoint.args)}
+	}
+	if err := creds.resolve(fs, false); err != nil {
+		return err
+	}
+	api, err := creds.apiClient()
+	if err != nil {
+		return err
+	}
+	resp, err := fn(api, fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	if *decode {
+		return writeDecoded(creds, stdout, resp)
+	}
+	return writeJSON(stdout, resp)
+}
+
+func printApiUsage() {
+	fmt.Fprintln(os.Stderr, "usage: applego api <endpoint> [flags] [args]")
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 1, ' ', 0)
+	for _, e := range apiEndpoints {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", e.name, e.args, e.usage)
+	}
+	_ = w.Flush()
+}
+
+// writeDecoded 解析响应中的 signed* 字段后输出
+func writeDecoded(creds *credentials, w io.Writer, resp any) error {
+	verifier, err := creds.verifier()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	var v any
+	if err = json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if err = decodeSignedFields(verifier, v); err != nil {
+		return err
+	}
+	return writeJSON(w, v)
+}
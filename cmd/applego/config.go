@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pkg6/applego/applepay"
+	"github.com/pkg6/applego/config"
+	"os"
+	"strconv"
+)
+
+// credentials 命令行参数优先，其次为环境变量（APPLEGO_*），最后为 -config 指定的YAML/JSON配置文件
+type credentials struct {
+	config     string
+	app        string
+	issuer     string
+	bundleID   string
+	keyID      string
+	privateKey string
+	keyFile    string
+	keyBase64  string
+	production string
+	password   string
+	teamID     string
+	clientID   string
+	baseURL    string
+	rootCert   string
+
+	resolved *config.App
+}
+
+func (c *credentials) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.config, "config", "", "YAML or JSON config file (env APPLEGO_CONFIG)")
+	fs.StringVar(&c.app, "app", "", "app name in the config file (env APPLEGO_APP)")
+	fs.StringVar(&c.issuer, "iss", "", "issuer id from App Store Connect (env APPLEGO_ISSUER)")
+	fs.StringVar(&c.bundleID, "bid", "", "app bundle id (env APPLEGO_BUNDLE_ID)")
+	fs.StringVar(&c.keyID, "kid", "", "private key id (env APPLEGO_KEY_ID)")
+	fs.StringVar(&c.privateKey, "private-key", "", "p8 private key PEM content (env APPLEGO_PRIVATE_KEY)")
+	fs.StringVar(&c.keyFile, "private-key-file", "", "p8 private key file (env APPLEGO_PRIVATE_KEY_FILE)")
+	fs.StringVar(&c.keyBase64, "private-key-base64", "", "base64 encoded PEM or DER private key (env APPLEGO_PRIVATE_KEY_BASE64)")
+	fs.StringVar(&c.production, "production", "", "use the production environment, true/false (env APPLEGO_PRODUCTION)")
+	fs.StringVar(&c.password, "password", "", "app shared secret for verifyReceipt (env APPLEGO_PASSWORD)")
+	fs.StringVar(&c.teamID, "team-id", "", "team id for Sign in with Apple (env APPLEGO_SIGN_IN_TEAM_ID)")
+	fs.StringVar(&c.clientID, "client-id", "", "services id for Sign in with Apple (env APPLEGO_SIGN_IN_CLIENT_ID)")
+	fs.StringVar(&c.baseURL, "base-url", "", "override the App Store Server API url (env APPLEGO_BASE_URL)")
+	fs.StringVar(&c.rootCert, "root", "", "trusted root certificate PEM file, default Apple Root CA - G3 (env APPLEGO_ROOT_CERT)")
+}
+
+// resolve 读取配置文件与环境变量后使用参数覆盖，signIn 为true时 -kid、-private-key* 作用于 Sign in with Apple 的私钥
+func (c *credentials) resolve(fs *flag.FlagSet, signIn bool) error {
+	if c.config == "" {
+		c.config = os.Getenv("APPLEGO_CONFIG")
+	}
+	if c.baseURL == "" {
+		c.baseURL = os.Getenv("APPLEGO_BASE_URL")
+	}
+	if c.rootCert == "" {
+		c.rootCert = os.Getenv("APPLEGO_ROOT_CERT")
+	}
+	cfg, err := config.LoadWithEnv(c.config, config.DefaultEnvPrefix)
+	if err != nil {
+		return err
+	}
+	app, err := cfg.App(c.app)
+	if err != nil {
+		if c.app != "" || c.config != "" {
+			return err
+		}
+		app = &config.App{Name: config.DefaultAppName}
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if set["iss"] {
+		app.Issuer = c.issuer
+	}
+	if set["bid"] {
+		app.BundleID = c.bundleID
+	}
+	if set["production"] {
+		if app.Production, err = strconv.ParseBool(c.production); err != nil {
+			return &usageError{"-production: " + err.Error()}
+		}
+	}
+	if set["password"] {
+		app.Password = c.password
+	}
+	if set["team-id"] || set["client-id"] || signIn {
+		if app.SignIn == nil {
+			app.SignIn = new(config.SignIn)
+		}
+		if set["team-id"] {
+			app.SignIn.TeamID = c.teamID
+		}
+		if set["client-id"] {
+			app.SignIn.ClientID = c.clientID
+		}
+	}
+	key := &app.Key
+	if signIn {
+		key = &app.SignIn.Key
+	}
+	if set["kid"] {
+		key.KeyID = c.keyID
+	}
+	switch {
+	case set["private-key"] && set["private-key-file"], set["private-key"] && set["private-key-base64"], set["private-key-file"] && set["private-key-base64"]:
+		return &usageError{"only one of -private-key, -private-key-file and -private-key-base64 can be set"}
+	case set["private-key"]:
+		key.PrivateKey, key.PrivateKeyFile, key.PrivateKeyBase64 = c.privateKey, "", ""
+	case set["private-key-file"]:
+		key.PrivateKey, key.PrivateKeyFile, key.PrivateKeyBase64 = "", c.keyFile, ""
+	case set["private-key-base64"]:
+		key.PrivateKey, key.PrivateKeyFile, key.PrivateKeyBase64 = "", "", c.keyBase64
+	}
+	c.resolved = app
+	return nil
+}
+
+func (c *credentials) apiClient() (*applepay.ApiClient, error) {
+	api, err := c.resolved.ApiClient()
+	if err != nil {
+		return nil, err
+	}
+	if c.baseURL != "" {
+		api.Client.SetBaseURL(c.baseURL)
+	}
+	return api, nil
+}
+
+func (c *credentials) verifier() (*applepay.SignedDataVerifier, error) {
+	if c.rootCert == "" {
+		return applepay.NewSignedDataVerifier(nil), nil
+	}
+	rootPEM, err := os.ReadFile(c.rootCert)
+	if err != nil {
+		return nil, fmt.Errorf("-root: %w", err)
+	}
+	return applepay.NewSignedDataVerifier(rootPEM), nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt"
+	"github.com/pkg6/applego/applepay"
+	"io"
+	"strings"
+)
+
+// runDecode 校验并解析 signedPayload、signedTransactionInfo、signedRenewalInfo 等JWS数据
+// 输入也可以是包含 signed* 字段的JSON，例如webhook的请求体或API的响应
+func runDecode(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("decode")
+	creds := new(credentials)
+	creds.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return &usageError{"expected one argument"}
+	}
+	if err := creds.resolve(fs, false); err != nil {
+		return err
+	}
+	verifier, err := creds.verifier()
+	if err != nil {
+		return err
+	}
+	input, err := readInput(fs.Arg(0), stdin)
+	if err != nil {
+		return err
+	}
+	if input == "" {
+		return &usageError{"empty input"}
+	}
+	if strings.HasPrefix(input, "{") {
+		var v map[string]any
+		if err = json.Unmarshal([]byte(input), &v); err != nil {
+			return err
+		}
+		if err = decodeSignedFields(verifier, v); err != nil {
+			return err
+		}
+		return writeJSON(stdout, v)
+	}
+	v, err := decodeSigned(verifier, input)
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, v)
+}
+
+// decodeSigned 解析JWS，通知会同时解析其中的交易与续订信息
+func decodeSigned(verifier *applepay.SignedDataVerifier, signed string) (any, error) {
+	claims := jwt2.MapClaims{}
+	if err := verifier.ExtractClaims(signed, &claims); err != nil {
+		return nil, err
+	}
+	if _, ok := claims["notificationType"]; ok {
+		return verifier.DecodeNotificationV2(signed, &applepay.NotificationV2DecodeOptions{AllowMissing: true})
+	}
+	return claims, nil
+}
+
+// decodeSignedFields 把 signedXxx 字段解析后以 xxx 为键放在同一层，数组同样处理
+func decodeSignedFields(verifier *applepay.SignedDataVerifier, v any) error {
+	switch val := v.(type) {
+	case map[string]any:
+		decoded := map[string]any{}
+		for key, field := range val {
+			name := decodedFieldName(key)
+			switch f := field.(type) {
+			case string:
+				if name == "" || f == "" {
+					continue
+				}
+				d, err := decodeSigned(verifier, f)
+				if err != nil {
+					return errors.New(key + ": " + err.Error())
+				}
+				decoded[name] = d
+			case []any:
+				if name == "" {
+					if err := decodeSignedFields(verifier, f); err != nil {
+						return err
+					}
+					continue
+				}
+				list := make([]any, 0, len(f))
+				for _, item := range f {
+					s, ok := item.(string)
+					if !ok {
+						continue
+					}
+					d, err := decodeSigned(verifier, s)
+					if err != nil {
+						return errors.New(key + ": " + err.Error())
+					}
+					list = append(list, d)
+				}
+				decoded[name] = list
+			default:
+				if err := decodeSignedFields(verifier, f); err != nil {
+					return err
+				}
+			}
+		}
+		for key, d := range decoded {
+			val[key] = d
+		}
+	case []any:
+		for _, item := range val {
+			if err := decodeSignedFields(verifier, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodedFieldName signedTransactionInfo => transactionInfo，非 signed 开头的字段返回空
+func decodedFieldName(key string) string {
+	if !strings.HasPrefix(key, "signed") || len(key) <= len("signed") || key == "signedDate" {
+		return ""
+	}
+	name := key[len("signed"):]
+	return strings.ToLower(name[:1]) + name[1:]
+}
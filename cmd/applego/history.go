@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pkg6/applego/applepay"
+	"io"
+	"strconv"
+	"time"
+)
+
+// notificationHistoryFlags 注册通知历史的查询条件，默认查询最近180天
+func notificationHistoryFlags(fs *flag.FlagSet) func() (*applepay.NotificationHistoryRequest, error) {
+	start := fs.String("start", "", "start date, RFC3339, 2006-01-02 or unix milliseconds (default 180 days ago)")
+	end := fs.String("end", "", "end date, RFC3339, 2006-01-02 or unix milliseconds (default now)")
+	notificationType := fs.String("type", "", "notification type")
+	subtype := fs.String("subtype", "", "notification subtype")
+	transactionId := fs.String("transaction-id", "", "transaction id")
+	onlyFailures := fs.Bool("only-failures", false, "only notifications that failed to reach the server")
+	return func() (*applepay.NotificationHistoryRequest, error) {
+		now := time.Now()
+		req := &applepay.NotificationHistoryRequest{
+			StartDate:           now.AddDate(0, 0, -180).UnixMilli(),
+			EndDate:             now.UnixMilli(),
+			NotificationType:    *notificationType,
+			NotificationSubtype: *subtype,
+			OnlyFailures:        *onlyFailures,
+			TransactionId:       *transactionId,
+		}
+		var err error
+		if *start != "" {
+			if req.StartDate, err = parseMilli(*start); err != nil {
+				return nil, &usageError{"-start: " + err.Error()}
+			}
+		}
+		if *end != "" {
+			if req.EndDate, err = parseMilli(*end); err != nil {
+				return nil, &usageError{"-end: " + err.Error()}
+			}
+		}
+		return req, nil
+	}
+}
+
+func parseMilli(s string) (int64, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ms, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UnixMilli(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid date %q", s)
+}
+
+// runHistory 获取通知历史的所有分页
+func runHistory(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("history")
+	creds := new(credentials)
+	creds.register(fs)
+	token := fs.String("token", "", "start from this pagination token")
+	maxPages := fs.Int("max-pages", 0, "stop after this many pages, 0 for all")
+	decode := fs.Bool("decode", false, "verify and decode signed payloads")
+	req := notificationHistoryFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return &usageError{"unexpected arguments"}
+	}
+	r, err := req()
+	if err != nil {
+		return err
+	}
+	if err = creds.resolve(fs, false); err != nil {
+		return err
+	}
+	api, err := creds.apiClient()
+	if err != nil {
+		return err
+	}
+	result := &applepay.ResponseNotificationHistory{PaginationToken: *token}
+	pages := 0
+	for {
+		resp, err := api.GetNotificationHistoryWithRequest(result.PaginationToken, r)
+		if err != nil {
+			return err
+		}
+		pages++
+		result.NotificationHistory = append(result.NotificationHistory, resp.NotificationHistory...)
+		result.HasMore, result.PaginationToken = resp.HasMore, resp.PaginationToken
+		if !resp.HasMore || (*maxPages > 0 && pages >= *maxPages) {
+			break
+		}
+	}
+	if *decode {
+		return writeDecoded(creds, stdout, result)
+	}
+	return writeJSON(stdout, result)
+}
@@ -0,0 +1,124 @@
+// applego App Store Server API、通知与票据的命令行工具，输出JSON
+//
+//	applego decode <signedPayload|@file|->
+//	applego client-secret -team-id TEAMID -client-id com.example.web -kid KEYID -private-key-file AuthKey.p8
+//	applego api transaction 2000000000000000
+//	applego history -start 2024-01-01 -end 2024-01-31
+//	applego receipt verify @receipt.txt
+//
+// 凭证通过参数、环境变量（APPLEGO_*）或 -config 指定的配置文件读取
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, stdin io.Reader, stdout io.Writer) error
+}
+
+var commands = []*command{
+	{"decode", "verify and print signed payload, transaction or renewal info", runDecode},
+	{"client-secret", "generate a Sign in with Apple client secret", runClientSecret},
+	{"api", "call an App Store Server API endpoint", runApi},
+	{"history", "fetch notification history, following pagination", runHistory},
+	{"receipt", "verify an app receipt with verifyReceipt", runReceipt},
+}
+
+// usageError 参数错误，退出码为2
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		printUsage(stderr)
+		return 2
+	}
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:], stdin, stdout)
+		var uerr *usageError
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.As(err, &uerr):
+			fmt.Fprintf(stderr, "applego %s: %v\n", cmd.name, err)
+			return 2
+		default:
+			fmt.Fprintf(stderr, "applego %s: %v\n", cmd.name, err)
+			return 1
+		}
+	}
+	fmt.Fprintf(stderr, "applego: unknown command %q\n", args[0])
+	printUsage(stderr)
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: applego <command> [flags] [args]")
+	fmt.Fprintln(w)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("applego "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// readInput 参数为 - 或为空时读取标准输入，以 @ 开头时读取文件
+func readInput(arg string, stdin io.Reader) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+	switch {
+	case arg == "" || arg == "-":
+		b, err = io.ReadAll(stdin)
+	case strings.HasPrefix(arg, "@"):
+		b, err = os.ReadFile(arg[1:])
+	default:
+		return strings.TrimSpace(arg), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// readFile 参数为 - 时读取标准输入，否则读取文件(可以带 @ 前缀)，内容保持原样
+func readFile(arg string, stdin io.Reader) ([]byte, error) {
+	if arg == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(strings.TrimPrefix(arg, "@"))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/pkg6/applego/applepay"
+	"io"
+)
+
+// runReceipt receipt verify 使用 verifyReceipt 校验票据，自动在正式与沙盒环境之间切换
+func runReceipt(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 || args[0] != "verify" {
+		return &usageError{"usage: applego receipt verify [flags] <receipt|@file|->"}
+	}
+	fs := newFlagSet("receipt verify")
+	creds := new(credentials)
+	creds.register(fs)
+	sandboxFirst := fs.Bool("sandbox-first", false, "request the sandbox environment first")
+	excludeOld := fs.Bool("exclude-old", false, "only include the latest renewal transaction for subscriptions")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return &usageError{"expected one argument"}
+	}
+	if err := creds.resolve(fs, false); err != nil {
+		return err
+	}
+	receipt, err := readInput(fs.Arg(0), stdin)
+	if err != nil {
+		return err
+	}
+	if receipt == "" {
+		return &usageError{"empty receipt"}
+	}
+	verifier := applepay.NewReceiptVerifier(creds.resolved.Password)
+	verifier.SandboxFirst = *sandboxFirst
+	verifier.ExcludeOldTransactions = *excludeOld
+	resp, err := verifier.Verify(context.Background(), receipt)
+	var statusErr *applepay.VerifyStatusError
+	if err != nil && !errors.As(err, &statusErr) {
+		return err
+	}
+	if werr := writeJSON(stdout, resp); werr != nil {
+		return werr
+	}
+	return err
+}
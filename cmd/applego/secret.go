@@ -0,0 +1,26 @@
+package main
+
+import (
+	"io"
+)
+
+// runClientSecret 生成 Sign in with Apple 的 client_secret，有效期180天
+func runClientSecret(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("client-secret")
+	creds := new(credentials)
+	creds.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return &usageError{"unexpected arguments"}
+	}
+	if err := creds.resolve(fs, true); err != nil {
+		return err
+	}
+	secret, err := creds.resolved.ClientSecret()
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, map[string]string{"clientSecret": secret})
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLoadYAML(t *testing.T) {
+	c, err := Load("test_config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, c.Validate(), nil)
+	app, err := c.App("")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, app.Name, "main")
+	api, err := app.ApiClient()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, api.Bid, "com.example.app")
+	secret, err := app.ClientSecret()
+	assert.Equal(t, err, nil)
+	assert.NotEqual(t, secret, "")
+
+	other, _ := c.App("other")
+	assert.Equal(t, other.Production, true)
+	_, err = c.App("missing")
+	assert.NotEqual(t, err, nil)
+
+	registry, err := c.ApiClientRegistry()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, registry.BundleIds(), []string{"com.example.app", "com.example.other"})
+	api, err = registry.Get("com.example.other", "Production")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, api.Bid, "com.example.other")
+}
+
+func TestValidate(t *testing.T) {
+	c, err := Load("test_config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Validate()
+	var errs ValidationErrors
+	assert.True(t, errors.As(err, &errs))
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, fields, []string{"issuer", "key_id", "sign_in.client_id", "sign_in.private_key"})
+	assert.Equal(t, errs[1].Error(), `config: app "main": key_id must be 10 characters, got 6`)
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("APPLEGO_APP", "other")
+	t.Setenv("APPLEGO_BUNDLE_ID", "com.example.env")
+	t.Setenv("APPLEGO_MAIN_PRODUCTION", "true")
+	c, err := LoadWithEnv("test_config.yaml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, _ := c.App("")
+	assert.Equal(t, app.Name, "other")
+	assert.Equal(t, app.BundleID, "com.example.env")
+	main, _ := c.App("main")
+	assert.Equal(t, main.Production, true)
+	assert.Equal(t, main.BundleID, "com.example.app")
+
+	// 默认app同时设置通用变量与带名称的变量时，带名称的变量优先
+	t.Setenv("APPLEGO_OTHER_BUNDLE_ID", "com.example.other.env")
+	for i := 0; i < 5; i++ {
+		c, err = LoadWithEnv("test_config.yaml", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		app, _ = c.App("")
+		assert.Equal(t, app.BundleID, "com.example.other.env")
+	}
+
+	t.Setenv("APPLEGO_APP", "")
+	t.Setenv("APPLEGO_ISSUER", "issuer")
+	t.Setenv("APPLEGO_KEY_ID", "ABCDEF1234")
+	t.Setenv("APPLEGO_PRIVATE_KEY_FILE", "test_AuthKey.p8")
+	c, err = FromEnv("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, c.Validate(), nil)
+	app, _ = c.App("")
+	assert.Equal(t, app.Name, DefaultAppName)
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultEnvPrefix 环境变量前缀
+const DefaultEnvPrefix = "APPLEGO"
+
+type envField struct {
+	name string
+	set  func(a *App, v string) error
+}
+
+var envFields = []envField{
+	{"ISSUER", func(a *App, v string) error { a.Issuer = v; return nil }},
+	{"BUNDLE_ID", func(a *App, v string) error { a.BundleID = v; return nil }},
+	{"KEY_ID", func(a *App, v string) error { a.KeyID = v; return nil }},
+	{"PRIVATE_KEY", func(a *App, v string) error { a.Key.setPrivateKey(v, "", ""); return nil }},
+	{"PRIVATE_KEY_FILE", func(a *App, v string) error { a.Key.setPrivateKey("", v, ""); return nil }},
+	{"PRIVATE_KEY_BASE64", func(a *App, v string) error { a.Key.setPrivateKey("", "", v); return nil }},
+	{"PRODUCTION", func(a *App, v string) (err error) { a.Production, err = strconv.ParseBool(v); return }},
+	{"PASSWORD", func(a *App, v string) error { a.Password = v; return nil }},
+	{"SIGN_IN_TEAM_ID", func(a *App, v string) error { a.signIn().TeamID = v; return nil }},
+	{"SIGN_IN_CLIENT_ID", func(a *App, v string) error { a.signIn().ClientID = v; return nil }},
+	{"SIGN_IN_KEY_ID", func(a *App, v string) error { a.signIn().KeyID = v; return nil }},
+	{"SIGN_IN_PRIVATE_KEY", func(a *App, v string) error { a.signIn().Key.setPrivateKey(v, "", ""); return nil }},
+	{"SIGN_IN_PRIVATE_KEY_FILE", func(a *App, v string) error { a.signIn().Key.setPrivateKey("", v, ""); return nil }},
+	{"SIGN_IN_PRIVATE_KEY_BASE64", func(a *App, v string) error { a.signIn().Key.setPrivateKey("", "", v); return nil }},
+}
+
+func (k *Key) setPrivateKey(content, file, b64 string) {
+	k.PrivateKey, k.PrivateKeyFile, k.PrivateKeyBase64 = content, file, b64
+}
+
+func (a *App) signIn() *SignIn {
+	if a.SignIn == nil {
+		a.SignIn = new(SignIn)
+	}
+	return a.SignIn
+}
+
+// ApplyEnv 使用环境变量覆盖配置
+// PREFIX_APP 指定默认app，PREFIX_ISSUER 等作用于默认app，PREFIX_<NAME>_ISSUER 等作用于名称为 name 的app，两者都设置时后者优先
+func (c *Config) ApplyEnv(prefix string) error {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	if v, ok := os.LookupEnv(prefix + "_APP"); ok {
+		c.Default = v
+	}
+	if c.Apps == nil {
+		c.Apps = map[string]*App{}
+	}
+	// 先应用 PREFIX_ISSUER 等通用变量，PREFIX_<NAME>_ISSUER 更具体，优先级更高
+	name := c.defaultName()
+	app, ok := c.Apps[name]
+	if !ok {
+		app = &App{Name: name}
+	}
+	if err := app.applyEnv(prefix + "_"); err != nil {
+		return err
+	}
+	if !ok && *app != (App{Name: name}) {
+		c.Apps[name] = app
+	}
+	for name, app := range c.Apps {
+		if err := app.applyEnv(prefix + "_" + envName(name) + "_"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *App) applyEnv(prefix string) error {
+	for _, f := range envFields {
+		v, ok := os.LookupEnv(prefix + f.name)
+		if !ok {
+			continue
+		}
+		if err := f.set(a, v); err != nil {
+			return fmt.Errorf("config: %s%s: %w", prefix, f.name, err)
+		}
+	}
+	return nil
+}
+
+func envName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// FromEnv 只从环境变量读取配置
+func FromEnv(prefix string) (*Config, error) {
+	c := new(Config)
+	if err := c.ApplyEnv(prefix); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadWithEnv 读取配置文件并使用环境变量覆盖，path 为空时只读取环境变量
+func LoadWithEnv(path, prefix string) (c *Config, err error) {
+	if path == "" {
+		return FromEnv(prefix)
+	}
+	if c, err = Load(path); err != nil {
+		return nil, err
+	}
+	if err = c.ApplyEnv(prefix); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
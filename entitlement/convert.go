@@ -0,0 +1,118 @@
+package entitlement
+
+import (
+	"github.com/pkg6/applego/applepay"
+	"github.com/pkg6/applego/utility"
+	"strconv"
+	"time"
+)
+
+func FromTransactionInfo(t *applepay.TransactionInfo) *Transaction {
+	return &Transaction{
+		TransactionId:               t.TransactionId,
+		OriginalTransactionId:       t.OriginalTransactionId,
+		ProductId:                   t.ProductId,
+		SubscriptionGroupIdentifier: t.SubscriptionGroupIdentifier,
+		Type:                        t.Type,
+		InAppOwnershipType:          t.InAppOwnershipType,
+		PurchaseDate:                milliTime(t.PurchaseDate),
+		ExpiresDate:                 milliTime(t.ExpiresDate),
+		RevocationDate:              milliTime(t.RevocationDate),
+		RevocationReason:            t.RevocationReason,
+		IsUpgraded:                  t.IsUpgraded,
+	}
+}
+
+func FromTransactionsItem(t *applepay.TransactionsItem) *Transaction {
+	return &Transaction{
+		TransactionId:               t.TransactionId,
+		OriginalTransactionId:       t.OriginalTransactionId,
+		ProductId:                   t.ProductId,
+		SubscriptionGroupIdentifier: t.SubscriptionGroupIdentifier,
+		Type:                        t.Type,
+		InAppOwnershipType:          t.InAppOwnershipType,
+		PurchaseDate:                milliTime(t.PurchaseDate),
+		ExpiresDate:                 milliTime(t.ExpiresDate),
+		RevocationDate:              milliTime(t.RevocationDate),
+		RevocationReason:            t.RevocationReason,
+		IsUpgraded:                  t.IsUpgraded,
+	}
+}
+
+// FromLatestReceiptInfo latest_receipt_info 中 cancellation_date 表示退款或家庭共享撤销
+func FromLatestReceiptInfo(t *applepay.LatestReceiptInfo) *Transaction {
+	tran := &Transaction{
+		TransactionId:               t.TransactionId,
+		OriginalTransactionId:       t.OriginalTransactionId,
+		ProductId:                   t.ProductId,
+		SubscriptionGroupIdentifier: t.SubscriptionGroupIdentifier,
+		InAppOwnershipType:          t.InAppOwnershipType,
+		PurchaseDate:                milliStrTime(t.PurchaseDateTimestamp),
+		ExpiresDate:                 milliStrTime(t.ExpiresDateTimestamp),
+		RevocationDate:              milliStrTime(t.CancellationDateTimestamp),
+		IsUpgraded:                  t.IsUpgraded == "true",
+	}
+	tran.RevocationReason, _ = strconv.Atoi(t.CancellationReason)
+	if !tran.ExpiresDate.IsZero() {
+		tran.Type = TypeAutoRenewable
+	}
+	return tran
+}
+
+func FromRenewalInfo(r *applepay.RenewalInfo) *Renewal {
+	return &Renewal{
+		OriginalTransactionId:  r.OriginalTransactionId,
+		ProductId:              r.ProductId,
+		AutoRenewProductId:     r.AutoRenewProductId,
+		AutoRenewStatus:        r.AutoRenewStatus == 1,
+		IsInBillingRetryPeriod: r.IsInBillingRetryPeriod,
+		GracePeriodExpiresDate: milliTime(r.GracePeriodExpiresDate),
+		ExpirationIntent:       int(r.ExpirationIntent),
+	}
+}
+
+func FromPendingRenewalInfo(r *applepay.PendingRenewalInfo) *Renewal {
+	renewal := &Renewal{
+		OriginalTransactionId:  r.OriginalTransactionId,
+		ProductId:              r.ProductId,
+		AutoRenewProductId:     r.AutoRenewProductId,
+		AutoRenewStatus:        r.AutoRenewStatus == "1",
+		IsInBillingRetryPeriod: r.IsInBillingRetryPeriod == "1",
+		GracePeriodExpiresDate: milliStrTime(r.GracePeriodExpiresDateTimestamp),
+	}
+	renewal.ExpirationIntent, _ = strconv.Atoi(r.ExpirationIntent)
+	return renewal
+}
+
+// EvaluateReceipt 根据 verifyReceipt 的响应计算权益
+func EvaluateReceipt(at time.Time, resp *applepay.VerifyResponse) *Result {
+	var transactions []*Transaction
+	var renewals []*Renewal
+	for _, info := range resp.LatestReceiptInfo {
+		transactions = append(transactions, FromLatestReceiptInfo(info))
+	}
+	if len(resp.LatestReceiptInfo) == 0 && resp.Receipt != nil {
+		for _, inApp := range resp.Receipt.InApp {
+			transactions = append(transactions, FromLatestReceiptInfo(inApp.ToLatestReceiptInfo()))
+		}
+	}
+	for _, info := range resp.PendingRenewalInfo {
+		renewals = append(renewals, FromPendingRenewalInfo(info))
+	}
+	return Evaluate(at, transactions, renewals)
+}
+
+func milliTime(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+func milliStrTime(ms string) time.Time {
+	t, err := utility.MilliStrToTime(ms)
+	if err != nil || t.UnixMilli() == 0 {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,245 @@
+package entitlement
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+type Status string
+
+const (
+	// StatusActive 订阅有效或非消耗型商品已购买
+	StatusActive Status = "ACTIVE"
+	// StatusGracePeriod 续订扣费失败，处于宽限期内，仍然可以使用
+	StatusGracePeriod Status = "GRACE_PERIOD"
+	// StatusBillingRetry 续订扣费失败，苹果仍在重试扣费，不可使用
+	StatusBillingRetry Status = "BILLING_RETRY"
+	// StatusExpired 订阅已过期
+	StatusExpired Status = "EXPIRED"
+	// StatusRevoked 家庭共享被撤销
+	StatusRevoked Status = "REVOKED"
+	// StatusRefunded 已退款
+	StatusRefunded Status = "REFUNDED"
+	// StatusUpgraded 已升级到同组中的其他订阅
+	StatusUpgraded Status = "UPGRADED"
+	// StatusConsumed 消耗型商品，购买后由业务发放，不提供持续的权益
+	StatusConsumed Status = "CONSUMED"
+)
+
+const (
+	TypeAutoRenewable  = "Auto-Renewable Subscription"
+	TypeNonConsumable  = "Non-Consumable"
+	TypeConsumable     = "Consumable"
+	TypeNonRenewing    = "Non-Renewing Subscription"
+	OwnershipPurchased = "PURCHASED"
+	OwnershipFamily    = "FAMILY_SHARED"
+)
+
+// statusPriority 同一订阅组内选择权益时的优先级，数值越小越优先
+var statusPriority = map[Status]int{
+	StatusActive:       0,
+	StatusGracePeriod:  1,
+	StatusBillingRetry: 2,
+	StatusExpired:      3,
+	StatusConsumed:     4,
+	StatusUpgraded:     5,
+	StatusRevoked:      6,
+	StatusRefunded:     7,
+}
+
+// HasAccess 该状态下用户是否可以使用
+func (s Status) HasAccess() bool {
+	return s == StatusActive || s == StatusGracePeriod
+}
+
+// Transaction 统一的交易数据，可以由 TransactionInfo、TransactionsItem、LatestReceiptInfo 转换得到
+type Transaction struct {
+	TransactionId               string
+	OriginalTransactionId       string
+	ProductId                   string
+	SubscriptionGroupIdentifier string
+	Type                        string
+	InAppOwnershipType          string
+	PurchaseDate                time.Time
+	ExpiresDate                 time.Time
+	RevocationDate              time.Time
+	RevocationReason            int
+	IsUpgraded                  bool
+}
+
+// Renewal 统一的续订数据，可以由 RenewalInfo、PendingRenewalInfo 转换得到
+type Renewal struct {
+	OriginalTransactionId  string
+	ProductId              string
+	AutoRenewProductId     string
+	AutoRenewStatus        bool
+	IsInBillingRetryPeriod bool
+	GracePeriodExpiresDate time.Time
+	ExpirationIntent       int
+}
+
+// Entitlement 单个商品或订阅组的权益
+type Entitlement struct {
+	ProductId                   string
+	SubscriptionGroupIdentifier string
+	OriginalTransactionId       string
+	TransactionId               string
+	Status                      Status
+	// Active 当前是否可以使用
+	Active       bool
+	FamilyShared bool
+	AutoRenew    bool
+	ExpiresDate  time.Time
+	// NonExpiring 没有过期时间，非消耗型商品或未设置时长的非续期订阅
+	NonExpiring bool
+	// GracePeriodExpiresDate 宽限期结束时间，仅 StatusGracePeriod 时有值
+	GracePeriodExpiresDate time.Time
+	// Reason 状态的原因说明
+	Reason string
+}
+
+// Result 权益计算结果
+type Result struct {
+	// At 计算权益的时间
+	At time.Time
+	// Products key为productId
+	Products map[string]*Entitlement
+	// Groups key为subscriptionGroupIdentifier
+	Groups map[string]*Entitlement
+}
+
+// HasAccess 商品或订阅组当前是否可以使用
+func (r *Result) HasAccess(productIdOrGroup string) bool {
+	if e, ok := r.Products[productIdOrGroup]; ok && e.Active {
+		return true
+	}
+	if e, ok := r.Groups[productIdOrGroup]; ok && e.Active {
+		return true
+	}
+	return false
+}
+
+// Options 计算权益的选项
+type Options struct {
+	// NonRenewingDurations 非续期订阅的时长，key为productId，过期时间为购买时间加时长
+	// 未设置时长的非续期订阅按不过期处理(NonExpiring)
+	NonRenewingDurations map[string]time.Duration
+}
+
+// Evaluate 根据交易与续订信息计算 at 时间点的权益
+// 每个商品取购买时间最新的交易，订阅组取组内优先级最高的商品
+func Evaluate(at time.Time, transactions []*Transaction, renewals []*Renewal) *Result {
+	return EvaluateWithOptions(at, transactions, renewals, Options{})
+}
+
+// EvaluateWithOptions 与 Evaluate 相同，非续期订阅使用 opts 中的时长计算过期时间
+func EvaluateWithOptions(at time.Time, transactions []*Transaction, renewals []*Renewal, opts Options) *Result {
+	result := &Result{
+		At:       at,
+		Products: make(map[string]*Entitlement),
+		Groups:   make(map[string]*Entitlement),
+	}
+	renewalByOriginal := make(map[string]*Renewal)
+	for _, r := range renewals {
+		renewalByOriginal[r.OriginalTransactionId] = r
+	}
+	latest := make(map[string]*Transaction)
+	for _, t := range transactions {
+		if t.PurchaseDate.After(at) {
+			continue
+		}
+		if current, ok := latest[t.ProductId]; !ok || t.PurchaseDate.After(current.PurchaseDate) {
+			latest[t.ProductId] = t
+		}
+	}
+	productIds := make([]string, 0, len(latest))
+	for productId := range latest {
+		productIds = append(productIds, productId)
+	}
+	sort.Strings(productIds)
+	for _, productId := range productIds {
+		t := latest[productId]
+		e := evaluate(at, t, renewalByOriginal[t.OriginalTransactionId], opts)
+		result.Products[productId] = e
+		if t.SubscriptionGroupIdentifier == "" {
+			continue
+		}
+		if current, ok := result.Groups[t.SubscriptionGroupIdentifier]; !ok || better(e, current) {
+			result.Groups[t.SubscriptionGroupIdentifier] = e
+		}
+	}
+	return result
+}
+
+func evaluate(at time.Time, t *Transaction, r *Renewal, opts Options) *Entitlement {
+	e := &Entitlement{
+		ProductId:                   t.ProductId,
+		SubscriptionGroupIdentifier: t.SubscriptionGroupIdentifier,
+		OriginalTransactionId:       t.OriginalTransactionId,
+		TransactionId:               t.TransactionId,
+		FamilyShared:                t.InAppOwnershipType == OwnershipFamily,
+		ExpiresDate:                 t.ExpiresDate,
+	}
+	if t.Type == TypeNonRenewing && e.ExpiresDate.IsZero() {
+		if d, ok := opts.NonRenewingDurations[t.ProductId]; ok {
+			e.ExpiresDate = t.PurchaseDate.Add(d)
+		}
+	}
+	if r != nil {
+		e.AutoRenew = r.AutoRenewStatus
+	}
+	switch {
+	case !t.RevocationDate.IsZero() && !t.RevocationDate.After(at) && e.FamilyShared:
+		e.Status = StatusRevoked
+		e.Reason = fmt.Sprintf("family sharing revoked at %s", t.RevocationDate.Format(time.RFC3339))
+	case !t.RevocationDate.IsZero() && !t.RevocationDate.After(at):
+		e.Status = StatusRefunded
+		e.Reason = fmt.Sprintf("refunded at %s, revocation reason %d", t.RevocationDate.Format(time.RFC3339), t.RevocationReason)
+	case t.IsUpgraded:
+		e.Status = StatusUpgraded
+		e.Reason = "upgraded to another subscription in the same group"
+	case t.Type == TypeConsumable:
+		e.Status = StatusConsumed
+		e.Reason = fmt.Sprintf("consumable purchased at %s", t.PurchaseDate.Format(time.RFC3339))
+	case e.ExpiresDate.IsZero():
+		e.Status = StatusActive
+		e.NonExpiring = true
+		e.Reason = fmt.Sprintf("%s purchased at %s", productType(t), t.PurchaseDate.Format(time.RFC3339))
+		if t.Type == TypeNonRenewing {
+			e.Reason += ", no duration configured, treated as non-expiring"
+		}
+	case e.ExpiresDate.After(at):
+		e.Status = StatusActive
+		e.Reason = fmt.Sprintf("expires at %s", e.ExpiresDate.Format(time.RFC3339))
+	case r != nil && r.IsInBillingRetryPeriod && r.GracePeriodExpiresDate.After(at):
+		e.Status = StatusGracePeriod
+		e.GracePeriodExpiresDate = r.GracePeriodExpiresDate
+		e.Reason = fmt.Sprintf("billing failed, grace period until %s", r.GracePeriodExpiresDate.Format(time.RFC3339))
+	case r != nil && r.IsInBillingRetryPeriod:
+		e.Status = StatusBillingRetry
+		e.Reason = fmt.Sprintf("billing failed, expired at %s, App Store is retrying", t.ExpiresDate.Format(time.RFC3339))
+	default:
+		e.Status = StatusExpired
+		e.Reason = fmt.Sprintf("expired at %s", e.ExpiresDate.Format(time.RFC3339))
+		if r != nil && r.ExpirationIntent > 0 {
+			e.Reason += fmt.Sprintf(", expiration intent %d", r.ExpirationIntent)
+		}
+	}
+	e.Active = e.Status.HasAccess()
+	return e
+}
+
+func better(e, current *Entitlement) bool {
+	if statusPriority[e.Status] != statusPriority[current.Status] {
+		return statusPriority[e.Status] < statusPriority[current.Status]
+	}
+	return e.ExpiresDate.After(current.ExpiresDate)
+}
+
+func productType(t *Transaction) string {
+	if t.Type == "" {
+		return "product"
+	}
+	return t.Type
+}
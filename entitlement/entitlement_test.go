@@ -0,0 +1,116 @@
+package entitlement
+
+import (
+	"github.com/pkg6/applego/applepay"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	transactions := []*Transaction{
+		{ProductId: "monthly", OriginalTransactionId: "1", SubscriptionGroupIdentifier: "g1", PurchaseDate: now.Add(-40 * day), ExpiresDate: now.Add(-10 * day)},
+		{ProductId: "monthly", OriginalTransactionId: "1", SubscriptionGroupIdentifier: "g1", PurchaseDate: now.Add(-10 * day), ExpiresDate: now.Add(20 * day)},
+		{ProductId: "yearly", OriginalTransactionId: "2", SubscriptionGroupIdentifier: "g2", PurchaseDate: now.Add(-400 * day), ExpiresDate: now.Add(-day)},
+		{ProductId: "weekly", OriginalTransactionId: "3", SubscriptionGroupIdentifier: "g3", PurchaseDate: now.Add(-8 * day), ExpiresDate: now.Add(-day)},
+		{ProductId: "pro", OriginalTransactionId: "4", Type: TypeNonConsumable, PurchaseDate: now.Add(-day), RevocationDate: now.Add(-time.Hour)},
+		{ProductId: "shared", OriginalTransactionId: "5", Type: TypeNonConsumable, InAppOwnershipType: OwnershipFamily, PurchaseDate: now.Add(-day), RevocationDate: now.Add(-time.Hour)},
+		{ProductId: "basic", OriginalTransactionId: "1", SubscriptionGroupIdentifier: "g1", PurchaseDate: now.Add(-50 * day), ExpiresDate: now.Add(-20 * day), IsUpgraded: true},
+		{ProductId: "lifetime", OriginalTransactionId: "6", Type: TypeNonConsumable, PurchaseDate: now.Add(-day)},
+		{ProductId: "coins", OriginalTransactionId: "7", Type: TypeConsumable, PurchaseDate: now.Add(-day)},
+		{ProductId: "season", OriginalTransactionId: "8", Type: TypeNonRenewing, PurchaseDate: now.Add(-100 * day)},
+	}
+	renewals := []*Renewal{
+		{OriginalTransactionId: "2", IsInBillingRetryPeriod: true, GracePeriodExpiresDate: now.Add(5 * day)},
+		{OriginalTransactionId: "3", IsInBillingRetryPeriod: true},
+	}
+	result := Evaluate(now, transactions, renewals)
+	assert.Equal(t, result.Products["monthly"].Status, StatusActive)
+	assert.Equal(t, result.Products["yearly"].Status, StatusGracePeriod)
+	assert.Equal(t, result.Products["weekly"].Status, StatusBillingRetry)
+	assert.Equal(t, result.Products["pro"].Status, StatusRefunded)
+	assert.Equal(t, result.Products["shared"].Status, StatusRevoked)
+	assert.Equal(t, result.Products["basic"].Status, StatusUpgraded)
+	assert.Equal(t, result.Products["lifetime"].Status, StatusActive)
+	assert.True(t, result.Products["lifetime"].NonExpiring)
+	assert.Equal(t, result.Products["coins"].Status, StatusConsumed)
+	assert.False(t, result.HasAccess("coins"))
+	// 没有设置时长的非续期订阅按不过期处理
+	assert.Equal(t, result.Products["season"].Status, StatusActive)
+	assert.True(t, result.Products["season"].NonExpiring)
+	assert.Equal(t, result.Groups["g1"].ProductId, "monthly")
+	assert.True(t, result.HasAccess("g2"))
+	assert.False(t, result.HasAccess("weekly"))
+
+	expired := Evaluate(now.Add(30*day), transactions, nil)
+	assert.Equal(t, expired.Products["monthly"].Status, StatusExpired)
+	assert.False(t, expired.HasAccess("g1"))
+}
+
+func TestEvaluateNonRenewing(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	transactions := []*Transaction{
+		{ProductId: "season", OriginalTransactionId: "1", Type: TypeNonRenewing, PurchaseDate: now.Add(-100 * day)},
+		{ProductId: "month", OriginalTransactionId: "2", Type: TypeNonRenewing, PurchaseDate: now.Add(-10 * day)},
+	}
+	opts := Options{NonRenewingDurations: map[string]time.Duration{"season": 90 * day, "month": 30 * day}}
+	result := EvaluateWithOptions(now, transactions, nil, opts)
+	assert.Equal(t, result.Products["season"].Status, StatusExpired)
+	assert.Equal(t, result.Products["season"].ExpiresDate, now.Add(-10*day))
+	assert.Equal(t, result.Products["month"].Status, StatusActive)
+	assert.Equal(t, result.Products["month"].ExpiresDate, now.Add(20*day))
+	assert.False(t, result.Products["month"].NonExpiring)
+}
+
+func TestFromTransactionsItem(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	purchase := now.Add(-24 * time.Hour).UnixMilli()
+	expires := now.Add(24 * time.Hour).UnixMilli()
+	revoked := FromTransactionsItem(&applepay.TransactionsItem{
+		TransactionId: "1", OriginalTransactionId: "1", ProductId: "monthly", SubscriptionGroupIdentifier: "g1",
+		Type: TypeAutoRenewable, InAppOwnershipType: OwnershipPurchased,
+		PurchaseDate: purchase, ExpiresDate: expires, RevocationDate: now.Add(-time.Hour).UnixMilli(), RevocationReason: 1,
+	})
+	assert.Equal(t, revoked.RevocationDate.UnixMilli(), now.Add(-time.Hour).UnixMilli())
+	assert.Equal(t, revoked.RevocationReason, 1)
+	upgraded := FromTransactionsItem(&applepay.TransactionsItem{
+		TransactionId: "2", OriginalTransactionId: "2", ProductId: "basic", SubscriptionGroupIdentifier: "g1",
+		Type: TypeAutoRenewable, PurchaseDate: purchase, ExpiresDate: expires, IsUpgraded: true,
+	})
+	assert.True(t, upgraded.IsUpgraded)
+
+	result := Evaluate(now, []*Transaction{revoked, upgraded}, nil)
+	assert.Equal(t, result.Products["monthly"].Status, StatusRefunded)
+	assert.Equal(t, result.Products["basic"].Status, StatusUpgraded)
+	assert.False(t, result.HasAccess("g1"))
+}
+
+func TestFromTransactionInfo(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	purchase := now.Add(-24 * time.Hour).UnixMilli()
+	transactions := []*Transaction{
+		FromTransactionInfo(&applepay.TransactionInfo{
+			TransactionId: "1", OriginalTransactionId: "1", ProductId: "pro", Type: TypeNonConsumable,
+			InAppOwnershipType: OwnershipFamily, PurchaseDate: purchase, RevocationDate: now.Add(-time.Hour).UnixMilli(),
+		}),
+		FromTransactionInfo(&applepay.TransactionInfo{
+			TransactionId: "2", OriginalTransactionId: "2", ProductId: "basic", SubscriptionGroupIdentifier: "g1",
+			Type: TypeAutoRenewable, PurchaseDate: purchase, ExpiresDate: now.Add(24 * time.Hour).UnixMilli(), IsUpgraded: true,
+		}),
+		FromTransactionInfo(&applepay.TransactionInfo{
+			TransactionId: "3", OriginalTransactionId: "3", ProductId: "coins", Type: TypeConsumable, PurchaseDate: purchase,
+		}),
+	}
+	assert.Equal(t, transactions[0].RevocationDate.UnixMilli(), now.Add(-time.Hour).UnixMilli())
+	assert.True(t, transactions[1].IsUpgraded)
+
+	result := Evaluate(now, transactions, nil)
+	assert.Equal(t, result.Products["pro"].Status, StatusRevoked)
+	assert.Equal(t, result.Products["basic"].Status, StatusUpgraded)
+	assert.Equal(t, result.Products["coins"].Status, StatusConsumed)
+	assert.False(t, result.HasAccess("pro"))
+	assert.False(t, result.HasAccess("g1"))
+}
@@ -0,0 +1,74 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"math/big"
+)
+
+// SigningMethodES256Signer 使用 crypto.Signer 签名的ES256，HSM/KMS 的签名器与 *ecdsa.PrivateKey 都可以使用
+// crypto.Signer 返回ASN.1 DER编码的签名，JWS 要求为32字节的 r 与 s 拼接
+var SigningMethodES256Signer jwt.SigningMethod = &signingMethodSigner{}
+
+type signingMethodSigner struct{}
+
+func (m *signingMethodSigner) Alg() string {
+	return jwt.SigningMethodES256.Alg()
+}
+
+// Verify 与 jwt.SigningMethodES256 相同，key 为 *ecdsa.PublicKey
+func (m *signingMethodSigner) Verify(signingString, signature string, key interface{}) error {
+	return jwt.SigningMethodES256.Verify(signingString, signature, key)
+}
+
+func (m *signingMethodSigner) Sign(signingString string, key interface{}) (string, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	if pub, ok := signer.Public().(*ecdsa.PublicKey); !ok || pub.Curve != elliptic.P256() {
+		return "", errors.New("ES256 signer must use an ecdsa P-256 key")
+	}
+	digest := sha256.Sum256([]byte(signingString))
+	der, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	raw, err := ASN1ToRawSignature(der, 32)
+	if err != nil {
+		return "", err
+	}
+	return jwt.EncodeSegment(raw), nil
+}
+
+// ASN1ToRawSignature 把ASN.1 DER编码的ECDSA签名转换为 r||s，size 为 r、s 各自的字节数，P-256为32
+func ASN1ToRawSignature(der []byte, size int) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("trailing data after ASN.1 signature")
+	}
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 || len(sig.R.Bytes()) > size || len(sig.S.Bytes()) > size {
+		return nil, errors.New("invalid ASN.1 signature")
+	}
+	out := make([]byte, 2*size)
+	sig.R.FillBytes(out[:size])
+	sig.S.FillBytes(out[size:])
+	return out, nil
+}
+
+// EncodeWithSigner 使用 crypto.Signer 生成ES256 token
+func EncodeWithSigner(signer crypto.Signer, claims jwt.Claims, header map[string]any) (string, error) {
+	return Encode(signer, SigningMethodES256Signer, claims, header)
+}
@@ -0,0 +1,69 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func derSignature(r, s *big.Int) []byte {
+	der, _ := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	return der
+}
+
+func TestASN1ToRawSignature(t *testing.T) {
+	short := new(big.Int).SetBytes([]byte{0x01})
+	s31 := new(big.Int).SetBytes(bytes.Repeat([]byte{0x7f}, 31))
+	full := new(big.Int).SetBytes(bytes.Repeat([]byte{0xff}, 32))
+	tooLarge := new(big.Int).SetBytes(bytes.Repeat([]byte{0x01}, 33))
+	tests := []struct {
+		name    string
+		der     []byte
+		want    []byte
+		wantErr bool
+	}{
+		{"full size", derSignature(full, full), append(full.FillBytes(make([]byte, 32)), full.FillBytes(make([]byte, 32))...), false},
+		{"short r and s are left padded", derSignature(short, s31), append(append(make([]byte, 31), 0x01), append([]byte{0}, s31.Bytes()...)...), false},
+		{"r larger than size", derSignature(tooLarge, short), nil, true},
+		{"s larger than size", derSignature(short, tooLarge), nil, true},
+		{"zero r", derSignature(big.NewInt(0), short), nil, true},
+		{"negative s", derSignature(short, big.NewInt(-1)), nil, true},
+		{"trailing bytes", append(derSignature(short, short), 0x00), nil, true},
+		{"malformed", []byte{0x30, 0x06, 0x02, 0x01}, nil, true},
+		{"empty", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ASN1ToRawSignature(tt.der, 32)
+			assert.Equal(t, err != nil, tt.wantErr)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestEncodeWithSigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := EncodeWithSigner(key, &jwt.StandardClaims{Issuer: "issuer"}, map[string]any{"alg": "ES256", "kid": "ABCDEF1234"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(strings.Split(token, ".")), 3)
+	claims := new(jwt.StandardClaims)
+	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (any, error) {
+		return &key.PublicKey, nil
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, claims.Issuer, "issuer")
+
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, err = EncodeWithSigner(p384, &jwt.StandardClaims{}, nil)
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,136 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"github.com/pkg6/applego/applepay"
+	"sync"
+)
+
+var (
+	// ErrOutOfOrder 通知的 signedDate 早于已处理的最新通知，通知未被应用
+	ErrOutOfOrder = errors.New("subscription: notification is older than current state")
+	// ErrDuplicate 该通知已经处理过
+	ErrDuplicate = errors.New("subscription: notification already applied")
+	// ErrNoOriginalTransaction 通知中没有交易信息，无法确定订阅
+	ErrNoOriginalTransaction = errors.New("subscription: notification has no originalTransactionId")
+)
+
+// Subscription 以 originalTransactionId 为唯一标识的订阅状态
+type Subscription struct {
+	OriginalTransactionId string `json:"originalTransactionId"`
+	ProductId             string `json:"productId"`
+	State                 State  `json:"state"`
+	AutoRenew             bool   `json:"autoRenew"`
+	ExpiresDate           int64  `json:"expiresDate"`
+	// LastSignedDate 最近一次应用的通知的 signedDate，用于判断乱序
+	LastSignedDate       int64  `json:"lastSignedDate"`
+	LastNotificationUUID string `json:"lastNotificationUUID"`
+}
+
+// Transition 一次状态变更
+type Transition struct {
+	OriginalTransactionId string `json:"originalTransactionId"`
+	From                  State  `json:"from"`
+	To                    State  `json:"to"`
+	NotificationType      string `json:"notificationType"`
+	Subtype               string `json:"subtype"`
+	NotificationUUID      string `json:"notificationUUID"`
+	SignedDate            int64  `json:"signedDate"`
+}
+
+// Store 订阅状态的持久化
+type Store interface {
+	// Get 订阅不存在时返回 nil, nil
+	Get(ctx context.Context, originalTransactionId string) (*Subscription, error)
+	Save(ctx context.Context, sub *Subscription) error
+}
+
+// Machine 由 App Store Server Notifications V2 驱动的订阅状态机
+type Machine struct {
+	Store Store
+	mu    sync.Mutex
+}
+
+func NewMachine(store Store) *Machine {
+	return &Machine{Store: store}
+}
+
+// Apply 应用一条已解析的通知
+// 通知早于当前状态时返回 ErrOutOfOrder，不允许的状态变更返回 *TransitionError，两种情况都不会修改存储的状态
+func (m *Machine) Apply(ctx context.Context, n *applepay.NotificationV2SignedPayloadResponse) (*Transition, error) {
+	if n.TransactionInfo == nil || n.TransactionInfo.OriginalTransactionId == "" {
+		return nil, ErrNoOriginalTransaction
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	originalTransactionId := n.TransactionInfo.OriginalTransactionId
+	sub, err := m.Store.Get(ctx, originalTransactionId)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		sub = &Subscription{OriginalTransactionId: originalTransactionId}
+	}
+	payload := n.Payload
+	if payload.NotificationUUID != "" && payload.NotificationUUID == sub.LastNotificationUUID {
+		return nil, ErrDuplicate
+	}
+	if payload.SignedDate < sub.LastSignedDate {
+		return nil, ErrOutOfOrder
+	}
+	to, err := Next(sub.State, payload.NotificationType, payload.Subtype)
+	if err != nil {
+		return nil, err
+	}
+	transition := &Transition{
+		OriginalTransactionId: originalTransactionId,
+		From:                  sub.State,
+		To:                    to,
+		NotificationType:      payload.NotificationType,
+		Subtype:               payload.Subtype,
+		NotificationUUID:      payload.NotificationUUID,
+		SignedDate:            payload.SignedDate,
+	}
+	sub.State = to
+	sub.LastSignedDate = payload.SignedDate
+	sub.LastNotificationUUID = payload.NotificationUUID
+	sub.ProductId = n.TransactionInfo.ProductId
+	if n.TransactionInfo.ExpiresDate > 0 {
+		sub.ExpiresDate = n.TransactionInfo.ExpiresDate
+	}
+	if n.RenewalInfo != nil {
+		sub.AutoRenew = n.RenewalInfo.AutoRenewStatus == 1
+	}
+	if err = m.Store.Save(ctx, sub); err != nil {
+		return nil, err
+	}
+	return transition, nil
+}
+
+// MemoryStore 基于内存的 Store，适用于测试
+type MemoryStore struct {
+	mu   sync.RWMutex
+	subs map[string]Subscription
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{subs: make(map[string]Subscription)}
+}
+
+func (s *MemoryStore) Get(_ context.Context, originalTransactionId string) (*Subscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sub, ok := s.subs[originalTransactionId]
+	if !ok {
+		return nil, nil
+	}
+	return &sub, nil
+}
+
+func (s *MemoryStore) Save(_ context.Context, sub *Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[sub.OriginalTransactionId] = *sub
+	return nil
+}
@@ -0,0 +1,59 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"github.com/pkg6/applego/applepay"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func notification(uuid, notificationType, subtype string, signedDate int64) *applepay.NotificationV2SignedPayloadResponse {
+	return &applepay.NotificationV2SignedPayloadResponse{
+		Payload: &applepay.NotificationV2Payload{
+			NotificationType: notificationType,
+			Subtype:          subtype,
+			NotificationUUID: uuid,
+			SignedDate:       signedDate,
+		},
+		TransactionInfo: &applepay.TransactionInfo{
+			OriginalTransactionId: "1000",
+			ProductId:             "monthly",
+			ExpiresDate:           signedDate + 1000,
+		},
+		RenewalInfo: &applepay.RenewalInfo{OriginalTransactionId: "1000", AutoRenewStatus: 1},
+	}
+}
+
+func TestMachineApply(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	machine := NewMachine(store)
+	steps := []struct {
+		n    *applepay.NotificationV2SignedPayloadResponse
+		want State
+	}{
+		{notification("1", applepay.NotificationTypeSubscribed, applepay.SubtypeInitialBuy, 100), StateActive},
+		{notification("2", applepay.NotificationTypeDidRenew, "", 200), StateActive},
+		{notification("3", applepay.NotificationTypeDidFailToRenew, applepay.SubtypeGracePeriod, 300), StateGracePeriod},
+		{notification("4", applepay.NotificationTypeGracePeriodExpired, "", 400), StateBillingRetry},
+		{notification("5", applepay.NotificationTypeExpired, applepay.SubtypeBillingRetry, 500), StateExpired},
+		{notification("6", applepay.NotificationTypeSubscribed, applepay.SubtypeResubscribe, 600), StateActive},
+		{notification("7", applepay.NotificationTypeRefund, "", 700), StateRefunded},
+	}
+	for _, step := range steps {
+		transition, err := machine.Apply(ctx, step.n)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, transition.To, step.want)
+	}
+	_, err := machine.Apply(ctx, notification("8", applepay.NotificationTypeDidRenew, "", 650))
+	assert.Equal(t, err, ErrOutOfOrder)
+	_, err = machine.Apply(ctx, notification("7", applepay.NotificationTypeRefund, "", 700))
+	assert.Equal(t, err, ErrDuplicate)
+	_, err = machine.Apply(ctx, notification("9", applepay.NotificationTypeDidRenew, "", 800))
+	var transitionErr *TransitionError
+	assert.True(t, errors.As(err, &transitionErr))
+	sub, _ := store.Get(ctx, "1000")
+	assert.Equal(t, sub.State, StateRefunded)
+	assert.Equal(t, sub.LastSignedDate, int64(700))
+}
@@ -0,0 +1,94 @@
+package subscription
+
+import (
+	"fmt"
+	"github.com/pkg6/applego/applepay"
+)
+
+type State string
+
+const (
+	// StateUnknown 尚未收到过该订阅的通知
+	StateUnknown      State = ""
+	StateActive       State = "ACTIVE"
+	StateGracePeriod  State = "GRACE_PERIOD"
+	StateBillingRetry State = "BILLING_RETRY"
+	StateExpired      State = "EXPIRED"
+	StateRefunded     State = "REFUNDED"
+	StateRevoked      State = "REVOKED"
+)
+
+// TransitionError 当前状态下不允许的通知
+type TransitionError struct {
+	From             State
+	NotificationType string
+	Subtype          string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("illegal transition from %q on %s/%s", e.From, e.NotificationType, e.Subtype)
+}
+
+type rule struct {
+	// from 允许的当前状态，为空表示任意状态
+	from []State
+	// to 为空表示状态不变
+	to State
+}
+
+var (
+	renewing = []State{StateActive, StateGracePeriod, StateBillingRetry}
+	lapsed   = []State{StateExpired, StateRefunded, StateRevoked}
+)
+
+// rules key为 notificationType 或 notificationType/subtype，优先匹配带subtype的规则
+// https://developer.apple.com/documentation/appstoreservernotifications/notificationtype
+var rules = map[string]rule{
+	applepay.NotificationTypeSubscribed:                                           {from: lapsed, to: StateActive},
+	applepay.NotificationTypeDidRenew:                                             {from: renewing, to: StateActive},
+	applepay.NotificationTypeDidFailToRenew:                                       {from: []State{StateActive}, to: StateBillingRetry},
+	applepay.NotificationTypeDidFailToRenew + "/" + applepay.SubtypeGracePeriod:   {from: []State{StateActive}, to: StateGracePeriod},
+	applepay.NotificationTypeGracePeriodExpired:                                   {from: []State{StateGracePeriod}, to: StateBillingRetry},
+	applepay.NotificationTypeExpired:                                              {from: renewing, to: StateExpired},
+	applepay.NotificationTypeRefund:                                               {to: StateRefunded},
+	applepay.NotificationTypeRefundReversed:                                       {from: []State{StateRefunded}, to: StateActive},
+	applepay.NotificationTypeRevoke:                                               {to: StateRevoked},
+	applepay.NotificationTypeRenewalExtended:                                      {to: StateActive},
+	applepay.NotificationTypeDidChangeRenewalPref + "/" + applepay.SubtypeUpgrade: {from: renewing, to: StateActive},
+	applepay.NotificationTypeDidChangeRenewalPref:                                 {from: renewing},
+	applepay.NotificationTypeDidChangeRenewalStatus:                               {},
+	applepay.NotificationTypeOfferRedeemed:                                        {},
+	applepay.NotificationTypePriceIncrease:                                        {},
+	applepay.NotificationTypeRefundDeclined:                                       {},
+	applepay.NotificationTypeConsumptionRequest:                                   {},
+	applepay.NotificationTypeRenewalExtension:                                     {},
+}
+
+// Next 根据通知计算下一个状态
+// 当前状态为 StateUnknown 时（例如接入前已存在的订阅），任何通知都被接受
+// 不改变状态的通知返回当前状态
+func Next(from State, notificationType, subtype string) (State, error) {
+	r, ok := rules[notificationType+"/"+subtype]
+	if !ok {
+		if r, ok = rules[notificationType]; !ok {
+			// 未知或与订阅状态无关的通知（TEST、METADATA_UPDATE等）不改变状态
+			return from, nil
+		}
+	}
+	if from != StateUnknown && len(r.from) > 0 && !containsState(r.from, from) {
+		return from, &TransitionError{From: from, NotificationType: notificationType, Subtype: subtype}
+	}
+	if r.to == StateUnknown {
+		return from, nil
+	}
+	return r.to, nil
+}
+
+func containsState(states []State, s State) bool {
+	for _, state := range states {
+		if state == s {
+			return true
+		}
+	}
+	return false
+}
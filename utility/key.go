@@ -1,33 +1,174 @@
 package utility
 
 import (
+	"bytes"
 	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"errors"
+	"fmt"
+	"strings"
 )
 
+var (
+	// ErrPrivateKeyNotFound 没有找到私钥内容
+	ErrPrivateKeyNotFound = errors.New("private key not found")
+	// ErrPrivateKeyInvalid 私钥内容无法解析
+	ErrPrivateKeyInvalid = errors.New("invalid private key")
+	// ErrPrivateKeyNotECDSA 私钥不是ECDSA私钥
+	ErrPrivateKeyNotECDSA = errors.New("private key must be ECDSA")
+	// ErrPrivateKeyCurve 私钥不是P-256曲线，App Store Connect 与 Sign in with Apple 的p8私钥都是P-256
+	ErrPrivateKeyCurve = errors.New("private key must use the P-256 curve")
+	// ErrPrivateKeyEncrypted 私钥已加密，需要使用 EcdsaPrivateKeyWithPassword
+	ErrPrivateKeyEncrypted = errors.New("private key is encrypted, password required")
+	// ErrPrivateKeyPassword 密码错误或加密的私钥已损坏
+	ErrPrivateKeyPassword = errors.New("incorrect password or corrupt encrypted private key")
+	// ErrPrivateKeyEncryption 不支持的私钥加密算法，只支持 PBES2(PBKDF2 + AES-CBC)
+	ErrPrivateKeyEncryption = errors.New("unsupported private key encryption")
+)
+
+// PrivateKeyError 私钥解析错误，Format 为识别出的格式，Err 可以通过 errors.Is 与 ErrPrivateKey* 比较
+type PrivateKeyError struct {
+	Format string
+	Err    error
+}
+
+func (e *PrivateKeyError) Error() string {
+	if e.Format == "" {
+		return "parse private key: " + e.Err.Error()
+	}
+	return fmt.Sprintf("parse private key (%s): %v", e.Format, e.Err)
+}
+
+func (e *PrivateKeyError) Unwrap() error {
+	return e.Err
+}
+
+// EcdsaPrivateKey 解析P-256私钥，支持：
+// PEM格式的 PKCS#8、SEC1 私钥(.p8文件内容)，没有 -----BEGIN----- 头尾的p8内容，
+// base64编码的PEM或DER(常用于环境变量)，以及DER
 func EcdsaPrivateKey(key []byte) (*ecdsa.PrivateKey, error) {
-	var err error
-	// Parse PEM block
-	var block *pem.Block
-	if block, _ = pem.Decode(key); block == nil {
-		return nil, errors.New("decode private key error")
-	}
-	// Parse the key
-	var parsedKey any
-	if parsedKey, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
-		if parsedKey, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
-			return nil, err
+	return EcdsaPrivateKeyWithPassword(key, nil)
+}
+
+// EcdsaPrivateKeyWithPassword 解析私钥，ENCRYPTED PRIVATE KEY 使用 password 解密
+// openssl pkcs8 -topk8 -v2 aes-256-cbc -in AuthKey.p8 -out AuthKey_encrypted.p8
+func EcdsaPrivateKeyWithPassword(key, password []byte) (*ecdsa.PrivateKey, error) {
+	key = bytes.TrimSpace(key)
+	if len(key) == 0 {
+		return nil, &PrivateKeyError{Err: ErrPrivateKeyNotFound}
+	}
+	if bytes.Contains(key, []byte("-----BEGIN")) {
+		return parsePEMPrivateKey(key, password)
+	}
+	// 没有头尾的p8内容或base64编码的PEM/DER
+	if der, err := decodeBase64(string(key)); err == nil {
+		if bytes.Contains(der, []byte("-----BEGIN")) {
+			return parsePEMPrivateKey(der, password)
+		}
+		return parseDERPrivateKey("base64 DER", der, password)
+	}
+	return parseDERPrivateKey("DER", key, password)
+}
+
+func parsePEMPrivateKey(key, password []byte) (*ecdsa.PrivateKey, error) {
+	// 环境变量中的私钥可能使用 \n 代替换行
+	if !bytes.Contains(key, []byte("\n")) {
+		key = bytes.ReplaceAll(key, []byte(`\n`), []byte("\n"))
+	}
+	for {
+		var block *pem.Block
+		block, key = pem.Decode(key)
+		if block == nil {
+			return nil, &PrivateKeyError{Format: "PEM", Err: ErrPrivateKeyNotFound}
+		}
+		format := "PEM " + block.Type
+		switch block.Type {
+		case "PRIVATE KEY":
+			k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, &PrivateKeyError{Format: format, Err: fmt.Errorf("%w: %v", ErrPrivateKeyInvalid, err)}
+			}
+			return checkEcdsaPrivateKey(format, k)
+		case "EC PRIVATE KEY":
+			if _, ok := block.Headers["DEK-Info"]; ok {
+				return nil, &PrivateKeyError{Format: format, Err: fmt.Errorf("%w: legacy PEM encryption, convert with openssl pkcs8 -topk8", ErrPrivateKeyEncryption)}
+			}
+			k, err := x509.ParseECPrivateKey(block.Bytes)
+			if err != nil {
+				return nil, &PrivateKeyError{Format: format, Err: fmt.Errorf("%w: %v", ErrPrivateKeyInvalid, err)}
+			}
+			return checkEcdsaPrivateKey(format, k)
+		case "ENCRYPTED PRIVATE KEY":
+			return parseEncryptedPrivateKey(format, block.Bytes, password)
+		case "RSA PRIVATE KEY", "DSA PRIVATE KEY", "OPENSSH PRIVATE KEY":
+			return nil, &PrivateKeyError{Format: format, Err: ErrPrivateKeyNotECDSA}
 		}
+		// 跳过 EC PARAMETERS 等其他块
 	}
-	pkey, ok := parsedKey.(*ecdsa.PrivateKey)
+}
+
+func parseDERPrivateKey(format string, der, password []byte) (*ecdsa.PrivateKey, error) {
+	if k, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return checkEcdsaPrivateKey(format, k)
+	}
+	if k, err := x509.ParseECPrivateKey(der); err == nil {
+		return checkEcdsaPrivateKey(format, k)
+	}
+	if isEncryptedPrivateKey(der) {
+		return parseEncryptedPrivateKey(format, der, password)
+	}
+	return nil, &PrivateKeyError{Format: format, Err: ErrPrivateKeyInvalid}
+}
+
+func parseEncryptedPrivateKey(format string, der, password []byte) (*ecdsa.PrivateKey, error) {
+	if len(password) == 0 {
+		return nil, &PrivateKeyError{Format: format, Err: ErrPrivateKeyEncrypted}
+	}
+	plain, err := decryptPKCS8(der, password)
+	if err != nil {
+		return nil, &PrivateKeyError{Format: format, Err: err}
+	}
+	k, err := x509.ParsePKCS8PrivateKey(plain)
+	if err != nil {
+		// 密码错误时填充可能碰巧正确，解密结果无法解析
+		return nil, &PrivateKeyError{Format: format, Err: ErrPrivateKeyPassword}
+	}
+	return checkEcdsaPrivateKey(format, k)
+}
+
+func checkEcdsaPrivateKey(format string, key any) (*ecdsa.PrivateKey, error) {
+	pkey, ok := key.(*ecdsa.PrivateKey)
 	if !ok {
-		return nil, errors.New("private key must be ECP private key")
+		return nil, &PrivateKeyError{Format: format, Err: fmt.Errorf("%w, got %T", ErrPrivateKeyNotECDSA, key)}
+	}
+	if pkey.Curve != elliptic.P256() {
+		return nil, &PrivateKeyError{Format: format, Err: fmt.Errorf("%w, got %s", ErrPrivateKeyCurve, pkey.Curve.Params().Name)}
 	}
 	return pkey, nil
 }
 
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// PublicKeyFingerprint 公钥的SHA-256指纹，格式与OpenSSH相同(SHA256:base64)，可以在日志中标识正在使用的私钥
+func PublicKeyFingerprint(pub *ecdsa.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
 func VerifyCert(rootPEM, certByte, intermediaCertStr []byte) error {
 	roots := x509.NewCertPool()
 	ok := roots.AppendCertsFromPEM(rootPEM)
@@ -0,0 +1,54 @@
+package utility
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"io"
+	"os"
+	"sync"
+)
+
+// FileSigner 从p8文件读取私钥的 crypto.Signer，用于本地开发；生产环境可以替换为 HSM/KMS 的签名器
+type FileSigner struct {
+	Path string
+
+	mu  sync.RWMutex
+	key *ecdsa.PrivateKey
+}
+
+// NewFileSigner 读取并解析私钥文件
+func NewFileSigner(path string) (*FileSigner, error) {
+	s := &FileSigner{Path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload 重新读取私钥文件，用于秘钥轮换
+func (s *FileSigner) Reload() error {
+	b, err := os.ReadFile(s.Path)
+	if err != nil {
+		return err
+	}
+	key, err := EcdsaPrivateKey(b)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.key = key
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *FileSigner) Public() crypto.PublicKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &s.key.PublicKey
+}
+
+func (s *FileSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.key.Sign(rand, digest, opts)
+}